@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+// terminalApprover asks the operator to approve every replacement in the terminal.
+type terminalApprover struct {
+	in  *bufio.Reader
+	out io.Writer
+	// progress renders the progress to the same terminal, so it's finished before every prompt. It may be nil.
+	progress progressRenderer
+}
+
+func newTerminalApprover(in io.Reader, out io.Writer, progress progressRenderer) *terminalApprover {
+	return &terminalApprover{in: bufio.NewReader(in), out: out, progress: progress}
+}
+
+func (a *terminalApprover) Approve(r bundle.Replacement) (bundle.Decision, error) {
+	// The bar redraws its line in place, so it would overwrite the prompt. The next report draws a new bar.
+	if a.progress != nil {
+		a.progress.Finish()
+	}
+	_, _ = fmt.Fprintf(a.out, "\nProposed replacement:\n%s\n", r)
+	for {
+		_, _ = fmt.Fprint(a.out, "[a]ccept, [s]kip or [p]in a different version? ")
+		answer, err := a.in.ReadString('\n')
+		if err != nil {
+			return bundle.Decision{}, fmt.Errorf("cannot read the answer. Error: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "a", "accept":
+			return bundle.Decision{Action: bundle.DecisionAccept}, nil
+		case "s", "skip":
+			return bundle.Decision{Action: bundle.DecisionSkip}, nil
+		case "p", "pin":
+			_, _ = fmt.Fprint(a.out, "Version: ")
+			version, err := a.in.ReadString('\n')
+			if err != nil {
+				return bundle.Decision{}, fmt.Errorf("cannot read the version. Error: %w", err)
+			}
+			if version = strings.TrimSpace(version); version != "" {
+				return bundle.Decision{Action: bundle.DecisionPin, Version: version}, nil
+			}
+		}
+	}
+}
+
+// isTerminal returns true if the file is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,153 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type DecisionAction int
+
+const (
+	// DecisionAccept means the replacement goes into the bundle.
+	DecisionAccept DecisionAction = iota
+	// DecisionSkip means the original package stays in the bundle and is not fixed.
+	DecisionSkip
+	// DecisionPin means the package is replaced with the version chosen by the operator instead.
+	DecisionPin
+)
+
+func (a DecisionAction) String() string {
+	switch a {
+	case DecisionSkip:
+		return "skipped"
+	case DecisionPin:
+		return "pinned"
+	default:
+		return "accepted"
+	}
+}
+
+// Replacement is a proposed replacement of a bundle package with another version of it.
+type Replacement struct {
+	Original *Package
+	New      *Package
+	// NewDependencies are the dependencies of the new package that the original package doesn't have.
+	NewDependencies []*Package
+	// SizeDelta is the size of the new package with its dependencies minus the size of the original one.
+	SizeDelta int64
+}
+
+// Decision is the operator's answer to a proposed replacement. Version is the pinned version.
+type Decision struct {
+	Action  DecisionAction
+	Version string
+}
+
+// Approver decides whether the proposed replacements go into the bundle.
+type Approver interface {
+	Approve(r Replacement) (Decision, error)
+}
+
+// ReplacementDecision records the decision made about a proposed replacement.
+type ReplacementDecision struct {
+	Name       string
+	OldVersion string
+	NewVersion string
+	Decision   Decision
+	// Automatic is true when the replacement was accepted without asking.
+	Automatic bool
+}
+
+func (d ReplacementDecision) String() string {
+	s := fmt.Sprintf("%s %s -> %s: %s", d.Name, d.OldVersion, d.NewVersion, d.Decision.Action)
+	if d.Decision.Action == DecisionPin {
+		s += " " + d.Decision.Version
+	}
+	if d.Automatic {
+		s += " automatically"
+	}
+	return s
+}
+
+// NewReplacement compares the new package with the original one.
+func NewReplacement(original, newPackage *Package) (Replacement, error) {
+	r := Replacement{Original: original, New: newPackage}
+	oldNames := make(map[string]bool)
+	for _, pp := range append([]*Package{original}, original.Dependencies...) {
+		oldNames[pp.Name] = true
+		info, err := pp.Stat()
+		if err != nil {
+			return r, fmt.Errorf("cannot stat package file %s. Error: %w", pp.Path, err)
+		}
+		r.SizeDelta -= info.Size()
+	}
+	for _, pp := range append([]*Package{newPackage}, newPackage.Dependencies...) {
+		if !oldNames[pp.Name] {
+			r.NewDependencies = append(r.NewDependencies, pp)
+		}
+		info, err := pp.Stat()
+		if err != nil {
+			return r, fmt.Errorf("cannot stat package file %s. Error: %w", pp.Path, err)
+		}
+		r.SizeDelta += info.Size()
+	}
+	return r, nil
+}
+
+// approveReplacement checks the replacement of the package against the policy, asks the bundle approver about it
+// and applies the decision. Without an approver, every replacement the policy allows is accepted.
+func approveReplacement(ctx context.Context, p, newPackage *Package, b *Bundle, res *FixResult) (*FixResult, error) {
+	r, err := NewReplacement(p, newPackage)
+	if err != nil {
+		res.AddLog("Couldn't compare the new version of the package with the original one due to the " +
+			"following error: " + err.Error())
+		return res, err
+	}
+	if violations := b.Policy.Violations(r); len(violations) > 0 {
+		for _, v := range violations {
+			res.addViolation(v)
+		}
+		res.AddLog(fmt.Sprintf("The policy doesn't allow to replace the package with version %s, so I'm not "+
+			"going to proceed.", newPackage.Version))
+		return res, nil
+	}
+	d := ReplacementDecision{Name: p.Name, OldVersion: p.Version, NewVersion: newPackage.Version}
+	if b.Approver == nil {
+		d.Automatic = true
+	} else {
+		d.Decision, err = b.Approver.Approve(r)
+		if err != nil {
+			res.AddLog("Couldn't get approval of the replacement due to the following error: " + err.Error())
+			return res, err
+		}
+	}
+	res.Decisions = append(res.Decisions, d)
+	switch d.Decision.Action {
+	case DecisionSkip:
+		res.AddLog(fmt.Sprintf("The replacement with version %s was skipped, so the package stays as it is.",
+			newPackage.Version))
+		return res, nil
+	case DecisionPin:
+		res.AddLog(fmt.Sprintf("The replacement with version %s was rejected in favour of version %s.",
+			newPackage.Version, d.Decision.Version))
+		return ReplaceWithVersion(ctx, p, b, d.Decision.Version, res)
+	default:
+		res.AddLog(fmt.Sprintf("The replacement with version %s was accepted.", newPackage.Version))
+		res.Success = true
+		res.Package = newPackage
+		return res, nil
+	}
+}
+
+func (r Replacement) String() string {
+	lines := []string{fmt.Sprintf("%s: %s -> %s, size delta %+d bytes", r.Original.Name, r.Original.Version,
+		r.New.Version, r.SizeDelta)}
+	if len(r.NewDependencies) == 0 {
+		lines = append(lines, "No new dependencies")
+	}
+	for _, d := range r.NewDependencies {
+		lines = append(lines, fmt.Sprintf("New dependency: %s - v%s", d.Name, d.Version))
+	}
+	return strings.Join(lines, "\n")
+}
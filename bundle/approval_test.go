@@ -0,0 +1,66 @@
+package bundle
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeApprover struct {
+	decision Decision
+	asked    []Replacement
+}
+
+func (a *fakeApprover) Approve(r Replacement) (Decision, error) {
+	a.asked = append(a.asked, r)
+	return a.decision, nil
+}
+
+func TestApproveReplacement(t *testing.T) {
+	m := &fakeManager{}
+	b, err := newSizedFakeBundle(m, map[string]int{
+		"chrony/chrony_3.2_amd64.deb":          100,
+		"chrony/libnss3_3.1_amd64.deb":         50,
+		"chrony=3.5/chrony_3.5_amd64.deb":      110,
+		"chrony=3.5/libnss3_3.2_amd64.deb":     60,
+		"chrony=3.5/libseccomp2_2.5_amd64.deb": 30,
+	})
+	if err != nil {
+		t.Fatalf("newSizedFakeBundle() error = %v", err)
+	}
+	original, newPackage := b.Packages[0], b.Packages[1]
+	tests := []struct {
+		name     string
+		approver Approver
+		want     ReplacementDecision
+		success  bool
+	}{
+		{"no approver", nil, ReplacementDecision{Name: "chrony", OldVersion: "3.2", NewVersion: "3.5",
+			Automatic: true}, true},
+		{"skip", &fakeApprover{decision: Decision{Action: DecisionSkip}}, ReplacementDecision{Name: "chrony",
+			OldVersion: "3.2", NewVersion: "3.5", Decision: Decision{Action: DecisionSkip}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.Approver = tt.approver
+			res, err := approveReplacement(context.Background(), original, newPackage, b, &FixResult{})
+			if err != nil {
+				t.Fatalf("approveReplacement() error = %v", err)
+			}
+			if res.Success != tt.success || len(res.Decisions) != 1 || res.Decisions[0] != tt.want {
+				t.Errorf("approveReplacement() success = %v, decisions = %v, want %v, %v", res.Success,
+					res.Decisions, tt.success, tt.want)
+			}
+			a, ok := tt.approver.(*fakeApprover)
+			if !ok {
+				return
+			}
+			r := a.asked[0]
+			// 110 + 60 + 30 bytes of the new packages replace 100 + 50 bytes of the original ones.
+			wantDelta := int64(50)
+			if len(r.NewDependencies) != 1 || r.NewDependencies[0].Name != "libseccomp2" || r.SizeDelta != wantDelta {
+				t.Errorf("Approve() replacement new dependencies = %v, size delta = %d, want libseccomp2, %d",
+					r.NewDependencies, r.SizeDelta, wantDelta)
+			}
+		})
+	}
+}
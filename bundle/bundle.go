@@ -1,14 +1,42 @@
 package bundle
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"io/fs"
 	"path"
 )
 
+// ArchitectureAll is the architecture of packages that can be installed on any architecture.
+const ArchitectureAll = "all"
+
+// toolName names the tool in the generated documents.
+const toolName = "konvoy-os-package-builder"
+
 type Bundle struct {
 	Manager  PackageManager
 	Packages []*Package
+	// Architecture is the architecture of the bundle packages. Empty means that all packages are
+	// architecture-independent.
+	Architecture string
+	Policy       Policy
+	// Approver approves replacements of the bundle packages. Nil means that all replacements are accepted.
+	Approver Approver
+	// Advisories are the security advisories to match the bundle packages against.
+	Advisories []Advisory
+	// PreferFixedVersions makes the solver replace the packages affected by the advisories with fixed versions
+	// when their versions are not essential.
+	PreferFixedVersions bool
+	// Quarantine lists the corrupt package files taken out of the bundle.
+	Quarantine []CorruptPackage
+	// Checkpoint saves the outcome of every fixed main package, so that a stopped run can continue. Nil means
+	// no checkpoints.
+	Checkpoint Checkpoint
+	// Installed are the versions of the base image packages by name. The fixed bundle drops the dependencies
+	// the base image already has. Nil means no pruning.
+	Installed map[string]string
 }
 
 func NewBundle(fileSystem fs.FS, manager PackageManager) (*Bundle, error) {
@@ -27,17 +55,68 @@ func NewBundle(fileSystem fs.FS, manager PackageManager) (*Bundle, error) {
 			return nil, fmt.Errorf("cannot create package from dir %s. Error: %w", entry.Name(), err)
 		}
 		b.Packages = append(b.Packages, p)
+		if b.Architecture, err = commonArchitecture(b.Architecture, p); err != nil {
+			return nil, fmt.Errorf("bundle contains packages of different architectures. Error: %w", err)
+		}
 	}
 	return b, nil
 }
 
+// BundleFile is a package file of the bundle tarball.
+type BundleFile struct {
+	// Path is the path of the file in the tarball.
+	Path    string
+	Package *Package
+}
+
+// Files returns the package files of the bundle in the tarball layout: every main package with its dependencies
+// in the package directory.
+func (b *Bundle) Files() []BundleFile {
+	files := make([]BundleFile, 0, len(b.Packages))
+	for _, p := range b.Packages {
+		dir := path.Base(path.Dir(p.Path))
+		for _, pp := range append([]*Package{p}, p.Dependencies...) {
+			files = append(files, BundleFile{Path: path.Join(dir, path.Base(pp.Path)), Package: pp})
+		}
+	}
+	return files
+}
+
+// PackageOrigin tells where the package file comes from.
+type PackageOrigin int
+
+const (
+	// OriginOriginal means the package comes from the original bundle.
+	OriginOriginal PackageOrigin = iota
+	// OriginDownloaded means the package manager downloaded the package.
+	OriginDownloaded
+	// OriginReplaced means the package manager downloaded the package to replace a main package of the bundle.
+	OriginReplaced
+)
+
+func (o PackageOrigin) String() string {
+	switch o {
+	case OriginDownloaded:
+		return "downloaded"
+	case OriginReplaced:
+		return "replaced"
+	default:
+		return "original"
+	}
+}
+
 type Package struct {
 	NameVersion
 	Path             string
+	Architecture     string
+	Origin           PackageOrigin
 	Dependencies     []*Package
 	VersionEssential bool
-	fileSystem       fs.FS
-	manager          PackageManager
+	// Corrupt means that the package file failed the integrity verification and must be downloaded again.
+	Corrupt    bool
+	fileSystem fs.FS
+	manager    PackageManager
+	control    *Control
 }
 
 type NameVersion struct {
@@ -64,6 +143,7 @@ func newPackageFile(fileSystem fs.FS, packageFilePath string, manager PackageMan
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse package name and version %s. Error: %w", packageFileName, err)
 	}
+	p.Architecture = manager.ParseArchitecture(packageFileName)
 	return p, nil
 }
 func newPackageDir(fileSystem fs.FS, packageDirPath string, manager PackageManager) (*Package, error) {
@@ -102,9 +182,36 @@ func newPackageDir(fileSystem fs.FS, packageDirPath string, manager PackageManag
 		return nil, fmt.Errorf("no candidates for the main package %s found", packageDirPath)
 	}
 	mainPackage.Dependencies = dependencies
+	if _, err = commonArchitecture("", mainPackage); err != nil {
+		return nil, fmt.Errorf("package directory %s contains packages of different architectures. Error: %w",
+			packageDirPath, err)
+	}
 	return mainPackage, nil
 }
 
+// commonArchitecture returns the architecture shared by the package, its dependencies and the given architecture.
+// Architecture-independent and unknown architectures are compatible with any other.
+func commonArchitecture(arch string, p *Package) (string, error) {
+	for _, pp := range append([]*Package{p}, p.Dependencies...) {
+		if pp.Architecture == "" || pp.Architecture == ArchitectureAll {
+			continue
+		}
+		if arch != "" && arch != pp.Architecture {
+			return "", fmt.Errorf("%s is %s, but %s expected", pp.Path, pp.Architecture, arch)
+		}
+		arch = pp.Architecture
+	}
+	return arch, nil
+}
+
+// SetOrigin sets the origin of the package and its dependencies.
+func (p *Package) SetOrigin(origin PackageOrigin) {
+	p.Origin = origin
+	for _, d := range p.Dependencies {
+		d.Origin = origin
+	}
+}
+
 func (p *Package) Open() (fs.File, error) {
 	return p.fileSystem.Open(p.Path)
 }
@@ -112,3 +219,19 @@ func (p *Package) Open() (fs.File, error) {
 func (p *Package) Stat() (fs.FileInfo, error) {
 	return fs.Stat(p.fileSystem, p.Path)
 }
+
+// Digest returns the size and the hex-encoded SHA-256 checksum of the package file.
+func (p *Package) Digest() (int64, string, error) {
+	f, err := p.Open()
+	if err != nil {
+		return 0, "", fmt.Errorf("cannot open package file %s. Error: %w", p.Path, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", fmt.Errorf("cannot read package file %s. Error: %w", p.Path, err)
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,204 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+)
+
+const (
+	checkpointKeyFile    = "key"
+	checkpointResultFile = "result.json"
+)
+
+// Checkpoint persists the outcome of every fixed main package, so that a stopped run can continue from where it
+// stopped.
+type Checkpoint interface {
+	// Load returns the saved outcome of the main package at the index of the bundle or nil if there is none.
+	Load(index int, p *Package) (*FixResult, error)
+	// Save saves the outcome of the main package at the index of the bundle.
+	Save(index int, res *FixResult) error
+}
+
+// DirCheckpoint keeps the outcomes and the package files of the fixed main packages in a work directory.
+// Every main package has its own subdirectory named after its index with the outcome and the package files.
+type DirCheckpoint struct {
+	dir     string
+	manager PackageManager
+}
+
+// OpenCheckpoint opens the checkpoint in the work directory. The key identifies the inputs of the run. When
+// the saved key differs, the saved outcomes belong to other inputs, so they are removed.
+func OpenCheckpoint(dir, key string, manager PackageManager) (*DirCheckpoint, error) {
+	c := &DirCheckpoint{dir: dir, manager: manager}
+	keyPath := path.Join(dir, checkpointKeyFile)
+	savedKey, err := os.ReadFile(keyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot read checkpoint key %s. Error: %w", keyPath, err)
+	}
+	if string(savedKey) == key {
+		return c, nil
+	}
+	if err = c.Remove(); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create checkpoint directory %s. Error: %w", dir, err)
+	}
+	if err = os.WriteFile(keyPath, []byte(key), 0644); err != nil {
+		return nil, fmt.Errorf("cannot write checkpoint key %s. Error: %w", keyPath, err)
+	}
+	return c, nil
+}
+
+// Remove removes the work directory with all the saved outcomes.
+func (c *DirCheckpoint) Remove() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("cannot remove checkpoint directory %s. Error: %w", c.dir, err)
+	}
+	return nil
+}
+
+// checkpointRecord is the saved outcome of a main package.
+type checkpointRecord struct {
+	Name string
+	// Dir is the package directory of the fixed package.
+	Dir         string
+	Success     bool
+	Log         []string
+	Chain       []string
+	Simulations []InstallResult
+	Decisions   []ReplacementDecision
+	Violations  []string
+	// Origins are the origins of the package files by the file name.
+	Origins map[string]PackageOrigin
+}
+
+func (c *DirCheckpoint) Load(index int, p *Package) (*FixResult, error) {
+	indexDir := path.Join(c.dir, strconv.Itoa(index))
+	resultPath := path.Join(indexDir, checkpointResultFile)
+	data, err := os.ReadFile(resultPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read checkpoint %s. Error: %w", resultPath, err)
+	}
+	var r checkpointRecord
+	if err = json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("cannot parse checkpoint %s. Error: %w", resultPath, err)
+	}
+	if r.Name != p.Name {
+		return nil, nil
+	}
+	res := &FixResult{Log: r.Log, Success: r.Success, Original: p, Package: p, Chain: r.Chain,
+		Simulations: r.Simulations, Decisions: r.Decisions, Violations: r.Violations}
+	if !r.Success {
+		return res, nil
+	}
+	res.Package, err = NewPackage(os.DirFS(indexDir), r.Dir, c.manager)
+	if err != nil {
+		return nil, fmt.Errorf("cannot restore package %s from checkpoint. Error: %w", p.Name, err)
+	}
+	for _, pp := range append([]*Package{res.Package}, res.Package.Dependencies...) {
+		pp.Origin = r.Origins[path.Base(pp.Path)]
+	}
+	return res, nil
+}
+
+func (c *DirCheckpoint) Save(index int, res *FixResult) error {
+	indexDir := path.Join(c.dir, strconv.Itoa(index))
+	if err := os.RemoveAll(indexDir); err != nil {
+		return fmt.Errorf("cannot clean checkpoint directory %s. Error: %w", indexDir, err)
+	}
+	r := checkpointRecord{Name: res.Original.Name, Success: res.Success, Log: res.Log, Chain: res.Chain,
+		Decisions: res.Decisions, Violations: res.Violations, Origins: make(map[string]PackageOrigin)}
+	for _, s := range res.Simulations {
+		// The simulated package is restored from the files.
+		s.Package = nil
+		r.Simulations = append(r.Simulations, s)
+	}
+	if res.Success {
+		r.Dir = path.Base(path.Dir(res.Package.Path))
+		packageDir := path.Join(indexDir, r.Dir)
+		if err := os.MkdirAll(packageDir, 0755); err != nil {
+			return fmt.Errorf("cannot create checkpoint directory %s. Error: %w", packageDir, err)
+		}
+		for _, pp := range append([]*Package{res.Package}, res.Package.Dependencies...) {
+			if err := copyPackage(pp, packageDir); err != nil {
+				return err
+			}
+			r.Origins[path.Base(pp.Path)] = pp.Origin
+		}
+	} else if err := os.MkdirAll(indexDir, 0755); err != nil {
+		return fmt.Errorf("cannot create checkpoint directory %s. Error: %w", indexDir, err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("cannot encode checkpoint of package %s. Error: %w", r.Name, err)
+	}
+	// The outcome is written last and renamed, so that an interrupted save leaves no outcome.
+	resultPath := path.Join(indexDir, checkpointResultFile)
+	if err = os.WriteFile(resultPath+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("cannot write checkpoint %s. Error: %w", resultPath, err)
+	}
+	if err = os.Rename(resultPath+".tmp", resultPath); err != nil {
+		return fmt.Errorf("cannot write checkpoint %s. Error: %w", resultPath, err)
+	}
+	return nil
+}
+
+func copyPackage(p *Package, dir string) error {
+	from, err := p.Open()
+	if err != nil {
+		return fmt.Errorf("cannot open package %s. Error: %w", p.Path, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer from.Close()
+	toPath := path.Join(dir, path.Base(p.Path))
+	to, err := os.Create(toPath)
+	if err != nil {
+		return fmt.Errorf("cannot create file %s. Error: %w", toPath, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer to.Close()
+	if _, err = io.Copy(to, from); err != nil {
+		return fmt.Errorf("cannot copy package %s to %s. Error: %w", p.Path, dir, err)
+	}
+	return nil
+}
+
+// CheckpointKey identifies the inputs of a run: the package files of the bundle, the fix options and the extra
+// inputs, for example the package sources. Runs with the same key give the same outcomes.
+func CheckpointKey(b *Bundle, opts FixOptions, extra ...string) (string, error) {
+	h := sha256.New()
+	files := b.Files()
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	for _, f := range files {
+		_, sum, err := f.Package.Digest()
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(h, "file %s %s\n", f.Path, sum)
+	}
+	options, err := json.Marshal(struct {
+		Architecture        string
+		Policy              Policy
+		Advisories          []Advisory
+		PreferFixedVersions bool
+		Extra               []string
+	}{b.Architecture, opts.Policy, opts.Advisories, opts.PreferFixedVersions, extra})
+	if err != nil {
+		return "", fmt.Errorf("cannot encode the fix options. Error: %w", err)
+	}
+	_, _ = h.Write(options)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
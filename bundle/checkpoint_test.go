@@ -0,0 +1,124 @@
+package bundle
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDirCheckpoint(t *testing.T) {
+	m := &fakeManager{}
+	b, err := newFakeBundle(m,
+		"chrony/chrony_3.2_amd64.deb",
+		"chrony/libseccomp_2.5_amd64.deb",
+		"ntp/ntp_4.2_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	chrony, ntp := b.Packages[0], b.Packages[1]
+	chrony.Dependencies[0].Origin = OriginDownloaded
+	dir := t.TempDir()
+	c, err := OpenCheckpoint(dir, "key", m)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() error = %v", err)
+	}
+	decision := ReplacementDecision{Name: "chrony", OldVersion: "3.1", NewVersion: "3.2",
+		Decision: Decision{Action: DecisionAccept}}
+	saved := []*FixResult{
+		{Log: []string{"SUCCESS"}, Success: true, Original: chrony, Package: chrony,
+			Decisions: []ReplacementDecision{decision}},
+		{Log: []string{"FAILED"}, Original: ntp, Package: ntp, Violations: []string{"ntp is denied"}},
+	}
+	for i, res := range saved {
+		if err = c.Save(i, res); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	c, err = OpenCheckpoint(dir, "key", m)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() error = %v", err)
+	}
+	res, err := c.Load(0, chrony)
+	if err != nil || res == nil {
+		t.Fatalf("Load() = %v, %v, want the saved result", res, err)
+	}
+	if !res.Success || !reflect.DeepEqual(res.Log, []string{"SUCCESS"}) ||
+		!reflect.DeepEqual(res.Decisions, []ReplacementDecision{decision}) || res.Original != chrony {
+		t.Errorf("Load() = %+v, want the saved result", res)
+	}
+	restored := b.clone()
+	restored.Packages[0] = res.Package
+	if got, want := fileOrigins(restored.Files()), fileOrigins(b.Files()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() restored files %v, want %v", got, want)
+	}
+	if res, err = c.Load(1, ntp); err != nil || res == nil || res.Success || res.Package != ntp ||
+		!reflect.DeepEqual(res.Violations, []string{"ntp is denied"}) {
+		t.Errorf("Load() = %+v, %v, want the saved failure", res, err)
+	}
+	if res, err = c.Load(1, chrony); err != nil || res != nil {
+		t.Errorf("Load() of another package = %v, %v, want nil", res, err)
+	}
+
+	c, err = OpenCheckpoint(dir, "other key", m)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() error = %v", err)
+	}
+	if res, err = c.Load(0, chrony); err != nil || res != nil {
+		t.Errorf("Load() with other inputs = %v, %v, want nil", res, err)
+	}
+}
+
+func TestFix_Checkpoint(t *testing.T) {
+	m := &fakeManager{}
+	b, err := newFakeBundle(m, "chrony/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb")
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	c, err := OpenCheckpoint(t.TempDir(), "key", m)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint() error = %v", err)
+	}
+	err = c.Save(0, &FixResult{Log: []string{"SUCCESS"}, Success: true, Original: b.Packages[0],
+		Package: b.Packages[0]})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	res, err := Fix(context.Background(), b, FixOptions{Checkpoint: c})
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	// The fake package manager cannot simulate installation of ntp, so only the restored chrony succeeds.
+	if want := []string{"ntp"}; !reflect.DeepEqual(res.Unresolved, want) {
+		t.Errorf("Fix() unresolved = %v, want %v", res.Unresolved, want)
+	}
+	// The error may go away on the next run, so the failure is not saved.
+	if saved, err := c.Load(1, b.Packages[1]); err != nil || saved != nil {
+		t.Errorf("Fix() saved %v, %v, want no outcome of ntp", saved, err)
+	}
+
+	// Now ntp fails because no version of it is installable, which is not going to change on the next run.
+	m.installs = map[string]InstallResult{"ntp": {Result: ResultHeldBrokenPackages, Details: []string{"ntp"}}}
+	m.versions = map[string][]string{}
+	res, err = Fix(context.Background(), b, FixOptions{Checkpoint: c})
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	if len(res.Results) != 2 || len(res.Results[1].Simulations) != 1 ||
+		res.Results[1].Simulations[0].Result != ResultHeldBrokenPackages {
+		t.Errorf("Fix() results = %v, want ntp simulated again", res.Results)
+	}
+	if saved, err := c.Load(1, b.Packages[1]); err != nil || saved == nil || saved.Success {
+		t.Errorf("Fix() saved %v, %v, want the failure of ntp", saved, err)
+	}
+}
+
+// fileOrigins returns the origins of the bundle files by the path.
+func fileOrigins(files []BundleFile) map[string]PackageOrigin {
+	origins := make(map[string]PackageOrigin, len(files))
+	for _, f := range files {
+		origins[f.Path] = f.Package.Origin
+	}
+	return origins
+}
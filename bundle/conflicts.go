@@ -0,0 +1,114 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+type IncompatibilityType int
+
+const (
+	// IncompatibilityDuplicate means the bundle contains the same package at different versions.
+	IncompatibilityDuplicate IncompatibilityType = iota
+	IncompatibilityConflicts
+	IncompatibilityBreaks
+)
+
+// Incompatibility is a pair of bundle packages that cannot be installed together as they are.
+type Incompatibility struct {
+	Type     IncompatibilityType
+	Package  *Package
+	Other    *Package
+	Relation Relation
+}
+
+func (i Incompatibility) String() string {
+	p := fmt.Sprintf("%s - v%s (%s)", i.Package.Name, i.Package.Version, i.Package.Path)
+	other := fmt.Sprintf("%s - v%s (%s)", i.Other.Name, i.Other.Version, i.Other.Path)
+	switch i.Type {
+	case IncompatibilityDuplicate:
+		return fmt.Sprintf("%s and %s are different versions of the same package", p, other)
+	case IncompatibilityConflicts:
+		return fmt.Sprintf("%s conflicts with %s: Conflicts: %s", p, other, i.Relation)
+	default:
+		return fmt.Sprintf("%s breaks %s: Breaks: %s", p, other, i.Relation)
+	}
+}
+
+// FindIncompatibilities analyzes Conflicts and Breaks of all the bundle packages and their dependencies and finds
+// the same packages at different versions. Replaces alone is not an incompatibility: the package takes over files
+// of the other one, and both can be installed together.
+func FindIncompatibilities(ctx context.Context, b *Bundle) ([]Incompatibility, error) {
+	packages := uniquePackages(b)
+	controls := make([]*Control, len(packages))
+	for i, p := range packages {
+		c, err := p.Control(ctx)
+		if err != nil {
+			return nil, err
+		}
+		controls[i] = c
+	}
+	incompatibilities := make([]Incompatibility, 0)
+	for i, p := range packages {
+		for j, other := range packages {
+			if i == j {
+				continue
+			}
+			if p.Name == other.Name {
+				if i < j {
+					incompatibilities = append(incompatibilities,
+						Incompatibility{Type: IncompatibilityDuplicate, Package: p, Other: other})
+				}
+				continue
+			}
+			if conflicts, ok := findRelation(b.Manager, controls[i].Conflicts, other, controls[j]); ok {
+				incompatibilities = append(incompatibilities,
+					Incompatibility{Type: IncompatibilityConflicts, Package: p, Other: other, Relation: conflicts})
+			}
+			if breaks, ok := findRelation(b.Manager, controls[i].Breaks, other, controls[j]); ok {
+				incompatibilities = append(incompatibilities,
+					Incompatibility{Type: IncompatibilityBreaks, Package: p, Other: other, Relation: breaks})
+			}
+		}
+	}
+	return incompatibilities, nil
+}
+
+// findRelation finds the relation that matches the package directly or through its Provides.
+func findRelation(m PackageManager, relations []Relation, p *Package, c *Control) (Relation, bool) {
+	for _, r := range relations {
+		if r.Name == p.Name && Satisfies(m, r, p.Version) {
+			return r, true
+		}
+		for _, provided := range c.Provides {
+			if r.Name != provided.Name {
+				continue
+			}
+			// Versioned relations are satisfied only by versioned provides.
+			if r.Operator == "" || (provided.Version != "" && Satisfies(m, r, provided.Version)) {
+				return r, true
+			}
+		}
+	}
+	return Relation{}, false
+}
+
+// uniquePackages returns all the bundle packages and dependencies without the same versions of the same packages.
+func uniquePackages(b *Bundle) []*Package {
+	seen := make(map[NameVersion]bool)
+	packages := make([]*Package, 0, len(b.Packages))
+	for _, p := range b.Packages {
+		for _, pp := range append([]*Package{p}, p.Dependencies...) {
+			if seen[pp.NameVersion] {
+				continue
+			}
+			seen[pp.NameVersion] = true
+			packages = append(packages, pp)
+		}
+	}
+	sort.SliceStable(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+	return packages
+}
@@ -0,0 +1,98 @@
+package bundle
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFindIncompatibilities(t *testing.T) {
+	type incompatibility struct {
+		Type    IncompatibilityType
+		Package string
+		Other   string
+	}
+	tests := []struct {
+		name     string
+		paths    []string
+		controls map[string]*Control
+		want     []incompatibility
+	}{
+		{
+			name:  "finds duplicate",
+			paths: []string{"chrony/chrony_3.2_amd64.deb", "chrony=3.5/chrony_3.5_amd64.deb"},
+			want:  []incompatibility{{IncompatibilityDuplicate, "chrony", "chrony"}},
+		},
+		{
+			name:     "finds conflicts",
+			paths:    []string{"chrony/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb"},
+			controls: map[string]*Control{"chrony": {Conflicts: []Relation{{Name: "ntp"}}}},
+			want:     []incompatibility{{IncompatibilityConflicts, "chrony", "ntp"}},
+		},
+		{
+			name:  "ignores conflicts with other versions",
+			paths: []string{"chrony/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb"},
+			controls: map[string]*Control{"chrony": {Conflicts: []Relation{
+				{Name: "ntp", Operator: "<<", Version: "4.0"},
+			}}},
+		},
+		{
+			name:  "finds breaks",
+			paths: []string{"chrony/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb"},
+			controls: map[string]*Control{"ntp": {Breaks: []Relation{
+				{Name: "chrony", Operator: "<<", Version: "3.5"},
+			}}},
+			want: []incompatibility{{IncompatibilityBreaks, "ntp", "chrony"}},
+		},
+		{
+			name:  "finds conflicts through versioned provides",
+			paths: []string{"chrony/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb"},
+			controls: map[string]*Control{
+				"chrony": {Conflicts: []Relation{{Name: "time-daemon", Operator: ">=", Version: "1.0"}}},
+				"ntp":    {Provides: []Relation{{Name: "time-daemon", Operator: "=", Version: "1.1"}}},
+			},
+			want: []incompatibility{{IncompatibilityConflicts, "chrony", "ntp"}},
+		},
+		{
+			name:  "ignores versioned conflicts through unversioned provides",
+			paths: []string{"chrony/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb"},
+			controls: map[string]*Control{
+				"chrony": {Conflicts: []Relation{{Name: "time-daemon", Operator: ">=", Version: "1.0"}}},
+				"ntp":    {Provides: []Relation{{Name: "time-daemon"}}},
+			},
+		},
+		{
+			name:     "ignores replaces without conflicts",
+			paths:    []string{"chrony/chrony_3.2_amd64.deb", "chrony/ntp_4.2_amd64.deb"},
+			controls: map[string]*Control{"chrony": {Replaces: []Relation{{Name: "ntp"}}}},
+		},
+		{
+			name:  "finds conflicts with replaces once",
+			paths: []string{"chrony/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb"},
+			controls: map[string]*Control{"chrony": {
+				Conflicts: []Relation{{Name: "ntp"}},
+				Replaces:  []Relation{{Name: "ntp"}},
+			}},
+			want: []incompatibility{{IncompatibilityConflicts, "chrony", "ntp"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := newFakeBundle(&fakeManager{controls: tt.controls}, tt.paths...)
+			if err != nil {
+				t.Fatalf("newFakeBundle() error = %v", err)
+			}
+			incompatibilities, err := FindIncompatibilities(context.Background(), b)
+			if err != nil {
+				t.Fatalf("FindIncompatibilities() error = %v", err)
+			}
+			got := make([]incompatibility, 0)
+			for _, i := range incompatibilities {
+				got = append(got, incompatibility{i.Type, i.Package.Name, i.Other.Name})
+			}
+			if want := append([]incompatibility{}, tt.want...); !reflect.DeepEqual(got, want) {
+				t.Errorf("FindIncompatibilities() = %v, want %v", got, want)
+			}
+		})
+	}
+}
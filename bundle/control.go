@@ -0,0 +1,72 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+)
+
+// Relation is a relationship to another package, for example "libc6 (>= 2.14)".
+type Relation struct {
+	Name string
+	// Operator is one of <<, <=, =, >=, >>. Empty operator means any version.
+	Operator string
+	Version  string
+}
+
+func (r Relation) String() string {
+	if r.Operator == "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%s (%s %s)", r.Name, r.Operator, r.Version)
+}
+
+// Control is the control information of a package file.
+type Control struct {
+	Name         string
+	Version      string
+	Architecture string
+	Maintainer   string
+	// Source and SourceVersion are the name and the version of the source package the package is built from.
+	Source        string
+	SourceVersion string
+	// Depends lists the dependencies including pre-dependencies. Every item lists alternatives.
+	Depends   [][]Relation
+	Conflicts []Relation
+	Breaks    []Relation
+	Replaces  []Relation
+	Provides  []Relation
+}
+
+// Control returns the control information of the package file. It is read once and cached.
+func (p *Package) Control(ctx context.Context) (*Control, error) {
+	if p.control != nil {
+		return p.control, nil
+	}
+	c, err := p.manager.ReadControl(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read control information of package %s. Error: %w", p.Path, err)
+	}
+	p.control = c
+	return c, nil
+}
+
+// Satisfies returns true if the version satisfies the relation.
+func Satisfies(m PackageManager, r Relation, version string) bool {
+	if r.Operator == "" {
+		return true
+	}
+	c := m.CompareVersions(version, r.Version)
+	switch r.Operator {
+	case "<<":
+		return c < 0
+	case "<=", "<":
+		return c <= 0
+	case "=":
+		return c == 0
+	case ">=", ">":
+		return c >= 0
+	case ">>":
+		return c > 0
+	}
+	return false
+}
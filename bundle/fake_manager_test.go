@@ -0,0 +1,161 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"testing/fstest"
+)
+
+var errNotSupported = errors.New("not supported by the fake package manager")
+
+// fakeManager reads packages named name_version_arch.deb and returns the control information given by name.
+type fakeManager struct {
+	controls map[string]*Control
+	licenses map[string][]string
+	// corrupt are the paths of the corrupt package files.
+	corrupt []string
+	// installs are the scripted results of the simulated installations by what is installed: the package name for
+	// CheckInstall, the package names joined with spaces for CheckInstallAll and the set formatted by formatSet
+	// for CheckInstallSet. When installs are set, the other installations fail with ResultUnknownProblem.
+	// Otherwise, the fake package manager doesn't support simulation.
+	installs map[string]InstallResult
+	// versions are the available versions by package name from the newest to the oldest.
+	versions map[string][]string
+	// checked are the sets passed to CheckInstallSet in order.
+	checked []string
+	// downloads are the package file names DownloadSet puts into the package directory by the set formatted by
+	// formatSet. Other downloads are not supported.
+	downloads map[string][]string
+}
+
+func (m *fakeManager) Name() string {
+	return "fake"
+}
+
+func (m *fakeManager) ParseNameVersion(packageFileName string) (NameVersion, error) {
+	parts := strings.Split(strings.Replace(packageFileName, "=", "_", -1), "_")
+	if len(parts) == 1 {
+		return NameVersion{Name: parts[0]}, nil
+	}
+	return NameVersion{Name: parts[0], Version: parts[1]}, nil
+}
+
+func (m *fakeManager) ParseArchitecture(packageFileName string) string {
+	parts := strings.Split(strings.TrimSuffix(packageFileName, ".deb"), "_")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
+
+func (m *fakeManager) IsMain(packageDirName, packageFileName string) bool {
+	return strings.HasPrefix(packageFileName, strings.Replace(packageDirName, "=", "_", -1)+"_")
+}
+
+func (m *fakeManager) ReadControl(_ context.Context, p *Package) (*Control, error) {
+	if c, ok := m.controls[p.Name]; ok {
+		return c, nil
+	}
+	return &Control{Name: p.Name, Version: p.Version}, nil
+}
+
+func (m *fakeManager) VerifyPackage(_ context.Context, p *Package) error {
+	if contains(m.corrupt, p.Path) {
+		return fmt.Errorf("%w: truncated", ErrCorruptPackage)
+	}
+	return nil
+}
+
+func (m *fakeManager) ReadLicenses(_ context.Context, p *Package) ([]string, error) {
+	return m.licenses[p.Name], nil
+}
+
+func (m *fakeManager) CheckInstall(_ context.Context, p *Package) (InstallResult, error) {
+	r, err := m.install(p.Name)
+	r.Package = p
+	return r, err
+}
+
+func (m *fakeManager) CheckInstallAll(_ context.Context, pp []*Package) (InstallResult, error) {
+	names := make([]string, len(pp))
+	for i, p := range pp {
+		names[i] = p.Name
+	}
+	return m.install(strings.Join(names, " "))
+}
+
+func (m *fakeManager) CheckInstallSet(_ context.Context, set []NameVersion) (InstallResult, error) {
+	m.checked = append(m.checked, formatSet(set))
+	return m.install(formatSet(set))
+}
+
+func (m *fakeManager) install(key string) (InstallResult, error) {
+	if m.installs == nil {
+		return InstallResult{}, errNotSupported
+	}
+	if r, ok := m.installs[key]; ok {
+		return r, nil
+	}
+	return InstallResult{Result: ResultUnknownProblem}, nil
+}
+
+func (m *fakeManager) ListVersions(_ context.Context, name string) ([]string, error) {
+	if m.versions == nil {
+		return nil, errNotSupported
+	}
+	return m.versions[name], nil
+}
+
+// CompareVersions compares versions as strings, which is enough for the test versions.
+func (m *fakeManager) CompareVersions(a, b string) int {
+	return strings.Compare(a, b)
+}
+
+func (m *fakeManager) UpdateDependencies(context.Context, *Package) error {
+	return errNotSupported
+}
+
+func (m *fakeManager) DownloadLatestVersion(context.Context, string) (*Package, error) {
+	return nil, errNotSupported
+}
+
+func (m *fakeManager) DownloadVersion(context.Context, string, string) (*Package, error) {
+	return nil, errNotSupported
+}
+
+func (m *fakeManager) DownloadSet(_ context.Context, packageDirName string, set []NameVersion) (*Package, error) {
+	files, ok := m.downloads[formatSet(set)]
+	if !ok {
+		return nil, errNotSupported
+	}
+	fileSystem := fstest.MapFS{}
+	for _, f := range files {
+		fileSystem[path.Join(packageDirName, f)] = &fstest.MapFile{Data: []byte(f)}
+	}
+	return NewPackage(fileSystem, packageDirName, m)
+}
+
+func (m *fakeManager) Clean() error {
+	return nil
+}
+
+// newFakeBundle creates a bundle from the package file paths.
+func newFakeBundle(m *fakeManager, packagePaths ...string) (*Bundle, error) {
+	fileSystem := fstest.MapFS{}
+	for _, p := range packagePaths {
+		fileSystem[p] = &fstest.MapFile{Data: []byte(p)}
+	}
+	return NewBundle(fileSystem, m)
+}
+
+// newSizedFakeBundle creates a bundle from the package files of the given sizes by path.
+func newSizedFakeBundle(m *fakeManager, sizes map[string]int) (*Bundle, error) {
+	fileSystem := fstest.MapFS{}
+	for p, size := range sizes {
+		fileSystem[p] = &fstest.MapFile{Data: make([]byte, size)}
+	}
+	return NewBundle(fileSystem, m)
+}
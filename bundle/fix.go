@@ -0,0 +1,61 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+)
+
+// FixOptions configures fixing of the bundle.
+type FixOptions struct {
+	Policy Policy
+	// Approver approves replacements of the bundle packages. Nil means that all replacements are accepted.
+	Approver Approver
+	// Advisories are the security advisories to match the fixed bundle against.
+	Advisories []Advisory
+	// PreferFixedVersions makes the solver replace the packages affected by the advisories with fixed versions
+	// when their versions are not essential.
+	PreferFixedVersions bool
+	// Progress receives the progress of the run. Nil means no progress reporting.
+	Progress ProgressReporter
+	// Checkpoint saves the outcome of every fixed main package and restores the saved outcomes instead of fixing
+	// the packages again. Nil means no checkpoints.
+	Checkpoint Checkpoint
+	// Installed are the versions of the base image packages by name. The fixed bundle drops the dependencies
+	// the base image already has at the same or a newer version. Nil means no pruning.
+	Installed map[string]string
+}
+
+// Fix checks and fixes a copy of the bundle with the options. It doesn't print anything and doesn't modify
+// the bundle: the fixed bundle, the outcome of every package and the unresolved packages are in the result.
+// The package manager of the bundle still downloads the new packages. Fix stops with an error when the context
+// is done.
+func Fix(ctx context.Context, b *Bundle, opts FixOptions) (*BundleFixResult, error) {
+	if b.Manager == nil {
+		return nil, errors.New("cannot fix the bundle without a package manager")
+	}
+	fixed := b.clone()
+	fixed.Policy = opts.Policy
+	fixed.Approver = opts.Approver
+	fixed.Advisories = opts.Advisories
+	fixed.PreferFixedVersions = opts.PreferFixedVersions
+	fixed.Checkpoint = opts.Checkpoint
+	fixed.Installed = opts.Installed
+	if opts.Progress != nil {
+		ctx = WithProgress(ctx, opts.Progress)
+	}
+	return CheckAndFixBundle(ctx, fixed)
+}
+
+// clone copies the bundle and its main packages, so that the solver can change the copy. Dependencies are
+// shared since the solver replaces them instead of changing.
+func (b *Bundle) clone() *Bundle {
+	c := *b
+	c.Packages = make([]*Package, len(b.Packages))
+	for i, p := range b.Packages {
+		pc := *p
+		pc.Dependencies = append([]*Package(nil), p.Dependencies...)
+		c.Packages[i] = &pc
+	}
+	c.Quarantine = append([]CorruptPackage(nil), b.Quarantine...)
+	return &c
+}
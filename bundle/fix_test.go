@@ -0,0 +1,80 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	b, err := newFakeBundle(&fakeManager{},
+		"chrony/chrony_3.2_amd64.deb",
+		"chrony/libseccomp_2.5_amd64.deb",
+		"ntp/ntp_4.2_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	packages := append([]*Package(nil), b.Packages...)
+	dependencies := append([]*Package(nil), b.Packages[0].Dependencies...)
+	res, err := Fix(context.Background(), b, FixOptions{Policy: Policy{Deny: []string{"chrony"}}})
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	if !reflect.DeepEqual(b.Packages, packages) || !reflect.DeepEqual(b.Packages[0].Dependencies, dependencies) {
+		t.Errorf("Fix() modified the bundle packages")
+	}
+	if res.Bundle == b || res.Bundle.Packages[0] == b.Packages[0] {
+		t.Errorf("Fix() returned the original bundle")
+	}
+	if !res.Bundle.Policy.Denies("chrony") || b.Policy.Denies("chrony") {
+		t.Errorf("Fix() didn't apply the options to the fixed bundle only")
+	}
+	// The fake package manager cannot simulate installation, so no package is fixed.
+	if want := []string{"chrony", "ntp"}; !reflect.DeepEqual(res.Unresolved, want) {
+		t.Errorf("Fix() unresolved = %v, want %v", res.Unresolved, want)
+	}
+	if len(res.Results) != 2 || res.Results[0].Success || len(res.Results[0].Log) == 0 {
+		t.Errorf("Fix() results = %v, want failed results with logs", res.Results)
+	}
+}
+
+func TestFix_NoManager(t *testing.T) {
+	if _, err := Fix(context.Background(), &Bundle{}, FixOptions{}); err == nil {
+		t.Errorf("Fix() error = nil, want an error")
+	}
+}
+
+func TestFix_Canceled(t *testing.T) {
+	b, err := newFakeBundle(&fakeManager{}, "chrony/chrony_3.2_amd64.deb")
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = Fix(ctx, b, FixOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Fix() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFix_Installed(t *testing.T) {
+	b, err := newFakeBundle(&fakeManager{}, "chrony/chrony_3.2_amd64.deb", "chrony/libseccomp2_2.5_amd64.deb")
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	res, err := Fix(context.Background(), b, FixOptions{Installed: map[string]string{"libseccomp2": "2.6"}})
+	if err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	if res.Pruned == nil || len(res.Pruned.Pruned) != 1 || len(res.Bundle.Packages[0].Dependencies) != 0 {
+		t.Errorf("Fix() pruned = %v, want libseccomp2 pruned", res.Pruned)
+	}
+	if len(b.Packages[0].Dependencies) != 1 {
+		t.Errorf("Fix() pruned the original bundle")
+	}
+	if !strings.Contains(res.InitialTree, "libseccomp2") || strings.Contains(res.FixedTree, "libseccomp2") {
+		t.Errorf("Fix() trees = %q, %q, want libseccomp2 only in the initial one", res.InitialTree, res.FixedTree)
+	}
+}
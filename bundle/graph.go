@@ -0,0 +1,157 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NodeStatus tells why the package is in the bundle.
+type NodeStatus int
+
+const (
+	NodeOriginal NodeStatus = iota
+	NodeReplaced
+	NodeDownloaded
+	// NodeUnresolved means the package could not be fixed.
+	NodeUnresolved
+)
+
+func (s NodeStatus) String() string {
+	switch s {
+	case NodeReplaced:
+		return "replaced"
+	case NodeDownloaded:
+		return "downloaded"
+	case NodeUnresolved:
+		return "unresolved"
+	default:
+		return "original"
+	}
+}
+
+var nodeColors = map[NodeStatus]string{
+	NodeOriginal:   "#d9d9d9",
+	NodeReplaced:   "#ffd966",
+	NodeDownloaded: "#9fc5e8",
+	NodeUnresolved: "#ea9999",
+}
+
+// Graph is the dependency graph of the bundle packages.
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+type GraphNode struct {
+	ID      string
+	Package *Package
+	Status  NodeStatus
+}
+
+// GraphEdge is a dependency of one bundle package on another. Label is the version constraint.
+type GraphEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+// NewGraph builds the dependency graph of the bundle from the control information of its packages.
+// Unresolved lists the names of the main packages that could not be fixed.
+func NewGraph(ctx context.Context, b *Bundle, unresolved []string) (*Graph, error) {
+	g := &Graph{}
+	packages := uniquePackages(b)
+	ids := make(map[*Package]string, len(packages))
+	for i, p := range packages {
+		ids[p] = fmt.Sprintf("n%d", i)
+		status := NodeOriginal
+		switch {
+		case contains(unresolved, p.Name):
+			status = NodeUnresolved
+		case p.Origin == OriginReplaced:
+			status = NodeReplaced
+		case p.Origin == OriginDownloaded:
+			status = NodeDownloaded
+		}
+		g.Nodes = append(g.Nodes, GraphNode{ID: ids[p], Package: p, Status: status})
+	}
+	for _, p := range packages {
+		control, err := p.Control(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, alternatives := range control.Depends {
+			for _, a := range alternatives {
+				for _, d := range packages {
+					if d == p || !satisfiedBy(ctx, b, a, d) {
+						continue
+					}
+					label := ""
+					if a.Operator != "" {
+						label = a.Operator + " " + a.Version
+					}
+					g.Edges = append(g.Edges, GraphEdge{From: ids[p], To: ids[d], Label: label})
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// satisfiedBy returns true if the package satisfies the relation directly or through Provides.
+func satisfiedBy(ctx context.Context, b *Bundle, r Relation, p *Package) bool {
+	if r.Name == p.Name {
+		return Satisfies(b.Manager, r, p.Version)
+	}
+	control, err := p.Control(ctx)
+	if err != nil {
+		return false
+	}
+	_, ok := findRelation(b.Manager, []Relation{r}, &Package{}, control)
+	return ok
+}
+
+// WriteDOT writes the graph in Graphviz DOT format.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	lines := []string{
+		"digraph bundle {",
+		"  rankdir=LR;",
+		"  node [shape=box, style=filled];",
+	}
+	for _, n := range g.Nodes {
+		lines = append(lines, fmt.Sprintf("  %s [label=\"%s\\n%s\\n(%s)\", fillcolor=\"%s\"];", n.ID,
+			n.Package.Name, n.Package.Version, n.Status, nodeColors[n.Status]))
+	}
+	for _, e := range g.Edges {
+		lines = append(lines, fmt.Sprintf("  %s -> %s [label=\"%s\"];", e.From, e.To, e.Label))
+	}
+	lines = append(lines, "}")
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	lines := []string{"graph LR"}
+	for _, n := range g.Nodes {
+		lines = append(lines, fmt.Sprintf("  %s[\"%s<br/>%s<br/>(%s)\"]:::%s", n.ID,
+			mermaidEscape(n.Package.Name), mermaidEscape(n.Package.Version), n.Status, n.Status))
+	}
+	for _, e := range g.Edges {
+		if e.Label == "" {
+			lines = append(lines, fmt.Sprintf("  %s --> %s", e.From, e.To))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s -->|\"%s\"| %s", e.From, mermaidEscape(e.Label), e.To))
+	}
+	for _, s := range []NodeStatus{NodeOriginal, NodeReplaced, NodeDownloaded, NodeUnresolved} {
+		lines = append(lines, fmt.Sprintf("  classDef %s fill:%s", s, nodeColors[s]))
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer("\"", "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
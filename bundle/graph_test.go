@@ -0,0 +1,57 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGraph_WriteDOT(t *testing.T) {
+	m := &fakeManager{controls: map[string]*Control{
+		"kubeadm": {Depends: [][]Relation{{{Name: "kubelet", Operator: ">=", Version: "1.13.0"}}, {{Name: "libc6"}}}},
+		"kubelet": {Depends: [][]Relation{{{Name: "cni", Operator: "=", Version: "0.8.7"}}}},
+		"cni":     {Provides: []Relation{{Name: "kubernetes-cni"}}},
+		"chrony":  {Depends: [][]Relation{{{Name: "kubernetes-cni"}}}},
+	}}
+	b, err := newFakeBundle(m,
+		"kubeadm=1.20.11/kubeadm_1.20.11_amd64.deb",
+		"kubeadm=1.20.11/cni_0.8.7_amd64.deb",
+		"kubelet=1.20.11/kubelet_1.20.11_amd64.deb",
+		"chrony/chrony_3.2_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	b.Packages[1].Dependencies[0].Origin = OriginReplaced
+	g, err := NewGraph(context.Background(), b, []string{"kubelet"})
+	if err != nil {
+		t.Fatalf("NewGraph() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err = g.WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT() error = %v", err)
+	}
+	want := `digraph bundle {
+  rankdir=LR;
+  node [shape=box, style=filled];
+  n0 [label="chrony\n3.2\n(original)", fillcolor="#d9d9d9"];
+  n1 [label="cni\n0.8.7\n(replaced)", fillcolor="#ffd966"];
+  n2 [label="kubeadm\n1.20.11\n(original)", fillcolor="#d9d9d9"];
+  n3 [label="kubelet\n1.20.11\n(unresolved)", fillcolor="#ea9999"];
+  n0 -> n1 [label=""];
+  n2 -> n3 [label=">= 1.13.0"];
+  n3 -> n1 [label="= 0.8.7"];
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteDOT() = %s, want %s", got, want)
+	}
+	buf.Reset()
+	if err = g.WriteMermaid(&buf); err != nil {
+		t.Fatalf("WriteMermaid() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `n2 -->|"#gt;= 1.13.0"| n3`) {
+		t.Errorf("WriteMermaid() = %s, want labeled edge from kubeadm to kubelet", buf.String())
+	}
+}
@@ -0,0 +1,69 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCorruptPackage is wrapped by the package manager errors about corrupt package files.
+var ErrCorruptPackage = errors.New("corrupt package file")
+
+// CorruptPackage is a package file of the bundle that failed the integrity verification.
+type CorruptPackage struct {
+	// Path is the path of the file in the bundle tarball.
+	Path    string
+	Package *Package
+	Err     error
+}
+
+func (c CorruptPackage) String() string {
+	return fmt.Sprintf("%s: %v", c.Path, c.Err)
+}
+
+// VerifyPackages verifies the integrity of every package file of the bundle and returns the corrupt ones.
+func VerifyPackages(ctx context.Context, b *Bundle) ([]CorruptPackage, error) {
+	corrupt := make([]CorruptPackage, 0)
+	verified := make(map[*Package]bool)
+	for _, f := range b.Files() {
+		if verified[f.Package] {
+			continue
+		}
+		verified[f.Package] = true
+		err := b.Manager.VerifyPackage(ctx, f.Package)
+		if errors.Is(err, ErrCorruptPackage) {
+			corrupt = append(corrupt, CorruptPackage{Path: f.Path, Package: f.Package, Err: err})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot verify package file %s. Error: %w", f.Path, err)
+		}
+	}
+	return corrupt, nil
+}
+
+// QuarantineCorruptPackages verifies the bundle and takes the corrupt dependencies out of it. The corrupt main
+// packages stay in the bundle marked as corrupt, so that the solver downloads them again. The corrupt packages
+// are added to the bundle quarantine and returned.
+func QuarantineCorruptPackages(ctx context.Context, b *Bundle) ([]CorruptPackage, error) {
+	corrupt, err := VerifyPackages(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	isCorrupt := make(map[*Package]bool, len(corrupt))
+	for _, c := range corrupt {
+		isCorrupt[c.Package] = true
+	}
+	for _, p := range b.Packages {
+		p.Corrupt = isCorrupt[p]
+		dependencies := make([]*Package, 0, len(p.Dependencies))
+		for _, d := range p.Dependencies {
+			if !isCorrupt[d] {
+				dependencies = append(dependencies, d)
+			}
+		}
+		p.Dependencies = dependencies
+	}
+	b.Quarantine = append(b.Quarantine, corrupt...)
+	return corrupt, nil
+}
@@ -0,0 +1,49 @@
+package bundle
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestQuarantineCorruptPackages(t *testing.T) {
+	m := &fakeManager{corrupt: []string{"chrony/chrony_3.2_amd64.deb", "chrony/libseccomp_2.5_amd64.deb"}}
+	b, err := newFakeBundle(m,
+		"chrony/chrony_3.2_amd64.deb",
+		"chrony/libseccomp_2.5_amd64.deb",
+		"chrony/tzdata_2021_all.deb",
+		"ntp/ntp_4.2_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	corrupt, err := QuarantineCorruptPackages(context.Background(), b)
+	if err != nil {
+		t.Fatalf("QuarantineCorruptPackages() error = %v", err)
+	}
+	paths := make([]string, len(corrupt))
+	for i, c := range corrupt {
+		paths[i] = c.Path
+	}
+	if !reflect.DeepEqual(paths, m.corrupt) {
+		t.Errorf("QuarantineCorruptPackages() = %v, want %v", paths, m.corrupt)
+	}
+	if !reflect.DeepEqual(b.Quarantine, corrupt) {
+		t.Errorf("QuarantineCorruptPackages() quarantine = %v, want %v", b.Quarantine, corrupt)
+	}
+	chrony, ntp := b.Packages[0], b.Packages[1]
+	if !chrony.Corrupt || ntp.Corrupt {
+		t.Errorf("QuarantineCorruptPackages() corrupt main packages = %v, %v, want true, false",
+			chrony.Corrupt, ntp.Corrupt)
+	}
+	if len(chrony.Dependencies) != 1 || chrony.Dependencies[0].Name != "tzdata" {
+		t.Errorf("QuarantineCorruptPackages() left dependencies %v, want tzdata only", chrony.Dependencies)
+	}
+	corrupt, err = VerifyPackages(context.Background(), b)
+	if err != nil {
+		t.Fatalf("VerifyPackages() error = %v", err)
+	}
+	if len(corrupt) != 1 || corrupt[0].Path != "chrony/chrony_3.2_amd64.deb" {
+		t.Errorf("VerifyPackages() = %v, want the corrupt main package only", corrupt)
+	}
+}
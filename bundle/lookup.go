@@ -0,0 +1,67 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// findDependency searches the bundle for a package that satisfies any of the dependency alternatives either
+// directly or through Provides. It returns nil when nothing satisfies the dependency and explains the result.
+func findDependency(ctx context.Context, p *Package, b *Bundle, alternatives []Relation) (*Package, string, error) {
+	candidates := uniquePackages(b)
+	mismatches := make([]string, 0)
+	for _, a := range alternatives {
+		for _, c := range candidates {
+			if c.Name == p.Name {
+				continue
+			}
+			if c.Name == a.Name {
+				if Satisfies(b.Manager, a, c.Version) {
+					return c, dependencyFound(p, c, a), nil
+				}
+				mismatches = append(mismatches, fmt.Sprintf("%s %s %s required, %s found",
+					a.Name, a.Operator, a.Version, c.Version))
+				continue
+			}
+			control, err := c.Control(ctx)
+			if err != nil {
+				return nil, "", err
+			}
+			for _, provided := range control.Provides {
+				if provided.Name != a.Name {
+					continue
+				}
+				// Versioned dependencies are satisfied only by versioned provides.
+				if a.Operator == "" || (provided.Version != "" && Satisfies(b.Manager, a, provided.Version)) {
+					return c, dependencyFound(p, c, a), nil
+				}
+				mismatches = append(mismatches, fmt.Sprintf("%s %s %s required, %s - v%s provides %s",
+					a.Name, a.Operator, a.Version, c.Name, c.Version, provided))
+			}
+		}
+	}
+	explanation := fmt.Sprintf("Couldn't find required dependency %s in the bundle.", printAlternatives(alternatives))
+	if len(mismatches) > 0 {
+		explanation += " " + strings.Join(mismatches, "; ") + "."
+	}
+	return nil, explanation, nil
+}
+
+func dependencyFound(p, dependency *Package, r Relation) string {
+	if hasDependency(p, dependency) {
+		return fmt.Sprintf("Package %s - v%s satisfies %s, but it's already among the dependencies.",
+			dependency.Name, dependency.Version, r)
+	}
+	return fmt.Sprintf("Package %s - v%s satisfies %s and was added to the dependencies.",
+		dependency.Name, dependency.Version, r)
+}
+
+func hasDependency(p, dependency *Package) bool {
+	for _, d := range p.Dependencies {
+		if d.Path == dependency.Path {
+			return true
+		}
+	}
+	return false
+}
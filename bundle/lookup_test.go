@@ -0,0 +1,88 @@
+package bundle
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_findDependency(t *testing.T) {
+	m := &fakeManager{controls: map[string]*Control{
+		"libcap": {Provides: []Relation{{Name: "libcap-abi", Operator: "=", Version: "2"}}},
+		"ntp":    {Provides: []Relation{{Name: "time-daemon"}}},
+	}}
+	b, err := newFakeBundle(m,
+		"chrony=3.2/chrony_3.2_amd64.deb",
+		"chrony=3.2/libseccomp2_2.5_amd64.deb",
+		"ntp/ntp_4.2_amd64.deb",
+		"ntp/libcap_2.2_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	chrony := b.Packages[0]
+	tests := []struct {
+		name            string
+		alternatives    []Relation
+		want            string
+		wantExplanation string
+	}{
+		{
+			name:            "finds version",
+			alternatives:    []Relation{{Name: "libcap", Operator: ">=", Version: "2.0"}},
+			want:            "libcap",
+			wantExplanation: "Package libcap - v2.2 satisfies libcap (>= 2.0) and was added to the dependencies.",
+		},
+		{
+			name:         "finds existing dependency",
+			alternatives: []Relation{{Name: "libseccomp2"}},
+			want:         "libseccomp2",
+			wantExplanation: "Package libseccomp2 - v2.5 satisfies libseccomp2, but it's already among " +
+				"the dependencies.",
+		},
+		{
+			name:         "explains version mismatch",
+			alternatives: []Relation{{Name: "libcap", Operator: ">=", Version: "3.0"}},
+			wantExplanation: "Couldn't find required dependency libcap (>= 3.0) in the bundle. " +
+				"libcap >= 3.0 required, 2.2 found.",
+		},
+		{
+			name:            "finds versioned provides",
+			alternatives:    []Relation{{Name: "libcap-abi", Operator: "=", Version: "2"}},
+			want:            "libcap",
+			wantExplanation: "Package libcap - v2.2 satisfies libcap-abi (= 2) and was added to the dependencies.",
+		},
+		{
+			name:         "explains unversioned provides of versioned dependency",
+			alternatives: []Relation{{Name: "time-daemon", Operator: ">=", Version: "1"}},
+			wantExplanation: "Couldn't find required dependency time-daemon (>= 1) in the bundle. " +
+				"time-daemon >= 1 required, ntp - v4.2 provides time-daemon.",
+		},
+		{
+			name:            "finds second alternative",
+			alternatives:    []Relation{{Name: "libfoo"}, {Name: "time-daemon"}},
+			want:            "ntp",
+			wantExplanation: "Package ntp - v4.2 satisfies time-daemon and was added to the dependencies.",
+		},
+		{
+			name:            "skips the package itself",
+			alternatives:    []Relation{{Name: "chrony"}},
+			wantExplanation: "Couldn't find required dependency chrony in the bundle.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, explanation, err := findDependency(context.Background(), chrony, b, tt.alternatives)
+			if err != nil {
+				t.Fatalf("findDependency() error = %v", err)
+			}
+			gotName := ""
+			if got != nil {
+				gotName = got.Name
+			}
+			if gotName != tt.want || explanation != tt.wantExplanation {
+				t.Errorf("findDependency() = %s, %q, want %s, %q", gotName, explanation, tt.want,
+					tt.wantExplanation)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package bundle
 
+import "context"
+
 type InstallResultType int
 
 const (
@@ -7,22 +9,92 @@ const (
 	ResultUnmetDependencies
 	ResultNewerAlreadyInstalled
 	ResultCannotFindPackage
+	// ResultEssentialRemoval means the installation would remove essential packages.
+	ResultEssentialRemoval
+	// ResultConflicts means the package conflicts with or breaks other packages.
+	ResultConflicts
+	// ResultHeldBrokenPackages means held or broken packages prevent the installation.
+	ResultHeldBrokenPackages
+	// ResultLockContention means another process holds the package manager lock.
+	ResultLockContention
+	// ResultCorruptArchive means the package file cannot be read.
+	ResultCorruptArchive
+	// ResultPackagesRemoved means the installation would remove other packages.
+	ResultPackagesRemoved
 	ResultUnknownProblem
 )
 
+func (t InstallResultType) String() string {
+	switch t {
+	case ResultOk:
+		return "ok"
+	case ResultUnmetDependencies:
+		return "unmet dependencies"
+	case ResultNewerAlreadyInstalled:
+		return "newer version already installed"
+	case ResultCannotFindPackage:
+		return "cannot find package"
+	case ResultEssentialRemoval:
+		return "essential packages would be removed"
+	case ResultConflicts:
+		return "conflicts"
+	case ResultHeldBrokenPackages:
+		return "held broken packages"
+	case ResultLockContention:
+		return "package manager is locked"
+	case ResultCorruptArchive:
+		return "corrupt archive"
+	case ResultPackagesRemoved:
+		return "packages would be removed"
+	default:
+		return "unknown problem"
+	}
+}
+
+// PackageManager resolves, simulates installation of and downloads packages. The methods that take a context stop
+// their work, including the launched processes, when the context is done.
 type PackageManager interface {
 	Name() string
 	ParseNameVersion(packageFileName string) (NameVersion, error)
+	// ParseArchitecture returns the architecture of the package file or empty string if it's unknown.
+	ParseArchitecture(packageFileName string) string
 	IsMain(packageDirName, packageFileName string) bool
-	CheckInstall(p *Package) (InstallResult, error)
-	CheckInstallLatestVersion(name string) (InstallResultType, error)
-	UpdateDependencies(p *Package) error
-	DownloadLatestVersion(packageName string) (*Package, error)
+	// ReadControl reads the control information of the package file.
+	ReadControl(ctx context.Context, p *Package) (*Control, error)
+	// VerifyPackage verifies the integrity of the package file. Errors about corrupt files wrap ErrCorruptPackage.
+	VerifyPackage(ctx context.Context, p *Package) error
+	// ReadLicenses reads the SPDX license expressions of the parts of the package file. It returns nothing when
+	// they are unknown.
+	ReadLicenses(ctx context.Context, p *Package) ([]string, error)
+	CheckInstall(ctx context.Context, p *Package) (InstallResult, error)
+	// CheckInstallAll checks if it's possible to install the packages with their dependencies together.
+	CheckInstallAll(ctx context.Context, pp []*Package) (InstallResult, error)
+	// CheckInstallSet checks if it's possible to install the given packages together.
+	// Empty version means the latest one.
+	CheckInstallSet(ctx context.Context, set []NameVersion) (InstallResult, error)
+	// ListVersions returns all available versions of the package from the newest to the oldest.
+	ListVersions(ctx context.Context, name string) ([]string, error)
+	// CompareVersions returns -1 if a < b, 0 if a == b and 1 if a > b.
+	CompareVersions(a, b string) int
+	UpdateDependencies(ctx context.Context, p *Package) error
+	DownloadLatestVersion(ctx context.Context, packageName string) (*Package, error)
+	DownloadVersion(ctx context.Context, packageName, version string) (*Package, error)
+	// DownloadSet downloads the given packages with their dependencies into the package directory.
+	// The first package of the set is the main one.
+	DownloadSet(ctx context.Context, packageDirName string, set []NameVersion) (*Package, error)
 	Clean() error
 }
 
 type InstallResult struct {
-	Result            InstallResultType
-	Package           *Package
-	UnmetDependencies []NameVersion
+	Result  InstallResultType
+	Package *Package
+	// UnmetDependencies lists the dependencies the package manager couldn't satisfy.
+	// Every item lists alternatives, any of which satisfies the dependency.
+	UnmetDependencies [][]Relation
+	// Details describe the result, for example the packages that would be removed or the lock file.
+	Details []string
+	// Transaction is the plan of the simulated installation.
+	Transaction Transaction
+	// Output is the raw output of the package manager.
+	Output string
 }
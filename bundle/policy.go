@@ -0,0 +1,169 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Policy restricts the fixes the solver is allowed to make.
+type Policy struct {
+	// AllowedRemovals lists the node packages that installation of the bundle packages may remove.
+	// Any other removal makes the installation fail.
+	AllowedRemovals []string `json:"allowedRemovals"`
+	// Deny lists the packages that must never be replaced with another version automatically.
+	Deny []string `json:"deny"`
+	// MaxVersionBump is the largest allowed change of the package version: patch, minor or major.
+	// Empty means any change.
+	MaxVersionBump string `json:"maxVersionBump"`
+	// NoNewDependencies lists the packages that must not gain new dependencies.
+	NoNewDependencies []string `json:"noNewDependencies"`
+}
+
+func ReadPolicy(r io.Reader) (Policy, error) {
+	var policy Policy
+	if err := json.NewDecoder(r).Decode(&policy); err != nil {
+		return policy, fmt.Errorf("cannot parse policy. Error: %w", err)
+	}
+	if policy.MaxVersionBump != "" {
+		if _, err := ParseVersionBump(policy.MaxVersionBump); err != nil {
+			return policy, fmt.Errorf("cannot parse policy. Error: %w", err)
+		}
+	}
+	return policy, nil
+}
+
+// DisallowedRemovals returns the removals of the transaction that the policy doesn't allow.
+func (p Policy) DisallowedRemovals(t Transaction) []Change {
+	disallowed := make([]Change, 0)
+	for _, c := range t.Removals {
+		if !contains(p.AllowedRemovals, c.Name) {
+			disallowed = append(disallowed, c)
+		}
+	}
+	return disallowed
+}
+
+// Denies returns true if the policy doesn't allow to replace the package.
+func (p Policy) Denies(name string) bool {
+	return contains(p.Deny, name)
+}
+
+// AllowsNewDependencies returns true if the policy allows the package to gain new dependencies.
+func (p Policy) AllowsNewDependencies(name string) bool {
+	return !contains(p.NoNewDependencies, name)
+}
+
+// AllowsBump returns true if the policy allows to change the package version from old to new.
+func (p Policy) AllowsBump(oldVersion, newVersion string) bool {
+	if p.MaxVersionBump == "" {
+		return true
+	}
+	maxBump, err := ParseVersionBump(p.MaxVersionBump)
+	if err != nil {
+		return false
+	}
+	return versionBump(oldVersion, newVersion) <= maxBump
+}
+
+// Violations returns why the policy doesn't allow the replacement. It returns nothing if the replacement is allowed.
+func (p Policy) Violations(r Replacement) []string {
+	name := r.Original.Name
+	violations := make([]string, 0)
+	if p.Denies(name) {
+		violations = append(violations, fmt.Sprintf("the policy denies replacing %s", name))
+	}
+	if !p.AllowsBump(r.Original.Version, r.New.Version) {
+		violations = append(violations, fmt.Sprintf("%s %s -> %s is a %s version bump, but the policy allows "+
+			"a %s version bump at most", name, r.Original.Version, r.New.Version,
+			versionBump(r.Original.Version, r.New.Version), p.MaxVersionBump))
+	}
+	if !p.AllowsNewDependencies(name) && len(r.NewDependencies) > 0 {
+		names := make([]string, len(r.NewDependencies))
+		for i, d := range r.NewDependencies {
+			names[i] = d.Name
+		}
+		violations = append(violations, fmt.Sprintf("%s %s would add new dependencies %s, but the policy "+
+			"doesn't allow %s to gain new dependencies", name, r.New.Version, strings.Join(names, ", "), name))
+	}
+	return violations
+}
+
+// VersionBump is the kind of a version change.
+type VersionBump int
+
+const (
+	BumpNone VersionBump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (v VersionBump) String() string {
+	switch v {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	default:
+		return "none"
+	}
+}
+
+func ParseVersionBump(s string) (VersionBump, error) {
+	for _, v := range []VersionBump{BumpPatch, BumpMinor, BumpMajor} {
+		if s == v.String() {
+			return v, nil
+		}
+	}
+	return BumpNone, fmt.Errorf("unknown version bump %q, expected patch, minor or major", s)
+}
+
+var versionComponentReg = regexp.MustCompile(`^[0-9]+`)
+
+// versionBump classifies the change between two versions in the [epoch:]major.minor.patch[-revision] form.
+// A different epoch is a major bump. Any change after the minor component is a patch bump.
+func versionBump(oldVersion, newVersion string) VersionBump {
+	if oldVersion == newVersion {
+		return BumpNone
+	}
+	oldEpoch, oldComponents := versionComponents(oldVersion)
+	newEpoch, newComponents := versionComponents(newVersion)
+	switch {
+	case oldEpoch != newEpoch || oldComponents[0] != newComponents[0]:
+		return BumpMajor
+	case oldComponents[1] != newComponents[1]:
+		return BumpMinor
+	default:
+		return BumpPatch
+	}
+}
+
+// versionComponents returns the epoch and the numeric prefixes of the major and minor version components.
+func versionComponents(v string) (string, [2]string) {
+	epoch := ""
+	if i := strings.Index(v, ":"); i >= 0 {
+		epoch, v = v[:i], v[i+1:]
+	}
+	var components [2]string
+	for i, c := range strings.SplitN(v, ".", 3) {
+		if i == len(components) {
+			break
+		}
+		components[i] = versionComponentReg.FindString(c)
+	}
+	return epoch, components
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
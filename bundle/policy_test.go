@@ -0,0 +1,88 @@
+package bundle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_DisallowedRemovals(t *testing.T) {
+	policy, err := ReadPolicy(strings.NewReader(`{"allowedRemovals": ["ntp"]}`))
+	if err != nil {
+		t.Fatalf("ReadPolicy() error = %v", err)
+	}
+	transaction := Transaction{
+		Installs: []Change{{Name: "chrony", NewVersion: "3.2-4ubuntu4.5"}},
+		Removals: []Change{{Name: "ntp", OldVersion: "1:4.2.8p10+dfsg-5ubuntu7.3"}, {Name: "apt", OldVersion: "1.6.12"}},
+	}
+	want := []Change{{Name: "apt", OldVersion: "1.6.12"}}
+	if got := policy.DisallowedRemovals(transaction); !reflect.DeepEqual(got, want) {
+		t.Errorf("DisallowedRemovals() = %v, want %v", got, want)
+	}
+}
+
+func TestReadPolicy_MaxVersionBump(t *testing.T) {
+	if _, err := ReadPolicy(strings.NewReader(`{"maxVersionBump": "huge"}`)); err == nil {
+		t.Errorf("ReadPolicy() error = nil, want unknown version bump")
+	}
+}
+
+func TestVersionBump(t *testing.T) {
+	tests := []struct {
+		oldVersion string
+		newVersion string
+		want       VersionBump
+	}{
+		{"1.20.11-00", "1.20.11-00", BumpNone},
+		{"1.20.11-00", "1.20.12-00", BumpPatch},
+		{"1.1.1-1ubuntu2.1~18.04.13", "1.1.1-1ubuntu2.1~18.04.14", BumpPatch},
+		{"1.20.11-00", "1.21.0-00", BumpMinor},
+		{"3.2-4ubuntu4.5", "3.5-1", BumpMinor},
+		{"1.4.12-1", "2.0.0-1", BumpMajor},
+		{"1:4.2.8p10+dfsg-5ubuntu7.3", "4.2.8p12-1", BumpMajor},
+	}
+	for _, tt := range tests {
+		if got := versionBump(tt.oldVersion, tt.newVersion); got != tt.want {
+			t.Errorf("versionBump(%s, %s) = %v, want %v", tt.oldVersion, tt.newVersion, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_Violations(t *testing.T) {
+	policy, err := ReadPolicy(strings.NewReader(
+		`{"deny": ["openssl"], "maxVersionBump": "minor", "noNewDependencies": ["containerd.io"]}`))
+	if err != nil {
+		t.Fatalf("ReadPolicy() error = %v", err)
+	}
+	replacement := func(name, oldVersion, newVersion string, newDependencies ...string) Replacement {
+		r := Replacement{
+			Original: &Package{NameVersion: NameVersion{Name: name, Version: oldVersion}},
+			New:      &Package{NameVersion: NameVersion{Name: name, Version: newVersion}},
+		}
+		for _, d := range newDependencies {
+			r.NewDependencies = append(r.NewDependencies, &Package{NameVersion: NameVersion{Name: d}})
+		}
+		return r
+	}
+	tests := []struct {
+		name        string
+		replacement Replacement
+		want        []string
+	}{
+		{"allowed", replacement("chrony", "3.2-4ubuntu4.5", "3.5-1", "libseccomp2"), []string{}},
+		{"denied", replacement("openssl", "1.1.1-1", "1.1.1-2"), []string{"the policy denies replacing openssl"}},
+		{"major bump", replacement("containerd.io", "1.4.12-1", "2.0.0-1"), []string{
+			"containerd.io 1.4.12-1 -> 2.0.0-1 is a major version bump, but the policy allows a minor version " +
+				"bump at most"}},
+		{"new dependencies", replacement("containerd.io", "1.4.12-1", "1.4.13-1", "libseccomp2", "runc"),
+			[]string{"containerd.io 1.4.13-1 would add new dependencies libseccomp2, runc, but the policy doesn't " +
+				"allow containerd.io to gain new dependencies"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Violations(tt.replacement); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Violations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
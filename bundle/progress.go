@@ -0,0 +1,180 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgressPhase is what the run is busy with for the current package.
+type ProgressPhase int
+
+const (
+	// PhaseSimulate means the package manager simulates installation.
+	PhaseSimulate ProgressPhase = iota
+	// PhaseDownload means the package manager downloads packages.
+	PhaseDownload
+	// PhaseCopy means the package manager copies the downloaded packages to the bundle.
+	PhaseCopy
+)
+
+func (p ProgressPhase) String() string {
+	switch p {
+	case PhaseDownload:
+		return "download"
+	case PhaseCopy:
+		return "copy"
+	default:
+		return "simulate"
+	}
+}
+
+// Progress is the state of the run.
+type Progress struct {
+	// Index is the 1-based index of the current main package out of Total.
+	Index   int
+	Total   int
+	Package string
+	Phase   ProgressPhase
+	// Bytes are the bytes downloaded or copied in the current phase.
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+// ProgressReporter receives the progress of the run. It's called from the goroutines of the package manager too.
+type ProgressReporter interface {
+	Report(p Progress)
+}
+
+// ProgressTracker keeps the state of the run and reports it on every change. The methods of a nil tracker do
+// nothing, so that the package managers can call them without checks.
+type ProgressTracker struct {
+	reporter ProgressReporter
+	started  time.Time
+	mu       sync.Mutex
+	current  Progress
+}
+
+type progressKey struct{}
+
+// WithProgress returns the context that carries the tracker of the run progress reported to the reporter.
+func WithProgress(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, &ProgressTracker{reporter: reporter, started: time.Now()})
+}
+
+// ProgressFrom returns the progress tracker of the context or nil if the context has none.
+func ProgressFrom(ctx context.Context) *ProgressTracker {
+	t, _ := ctx.Value(progressKey{}).(*ProgressTracker)
+	return t
+}
+
+// StartPackage reports that the run starts fixing the main package.
+func (t *ProgressTracker) StartPackage(index, total int, name string) {
+	t.update(func(p *Progress) {
+		*p = Progress{Index: index, Total: total, Package: name}
+	})
+}
+
+// SetPhase reports that the current package enters the phase.
+func (t *ProgressTracker) SetPhase(phase ProgressPhase) {
+	t.update(func(p *Progress) {
+		p.Phase, p.Bytes = phase, 0
+	})
+}
+
+// SetBytes reports the bytes downloaded or copied in the current phase so far.
+func (t *ProgressTracker) SetBytes(n int64) {
+	t.update(func(p *Progress) {
+		p.Bytes = n
+	})
+}
+
+// AddBytes adds the bytes to the current phase.
+func (t *ProgressTracker) AddBytes(n int64) {
+	t.update(func(p *Progress) {
+		p.Bytes += n
+	})
+}
+
+func (t *ProgressTracker) update(fn func(p *Progress)) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.current)
+	t.current.Elapsed = time.Since(t.started)
+	t.reporter.Report(t.current)
+}
+
+// progressLine describes the progress, for example "[3/25] kubelet: download 12.3 MiB, 1m5s elapsed".
+func progressLine(p Progress) string {
+	line := fmt.Sprintf("[%d/%d] %s: %s", p.Index, p.Total, p.Package, p.Phase)
+	if p.Bytes > 0 {
+		line += " " + formatSize(p.Bytes)
+	}
+	return fmt.Sprintf("%s, %v elapsed", line, p.Elapsed.Round(time.Second))
+}
+
+// LineRenderer writes the progress line by line for logs without a terminal. It writes a line when the package or
+// the phase changes and at most once per interval when only the bytes change.
+type LineRenderer struct {
+	w        io.Writer
+	interval time.Duration
+	last     Progress
+	written  time.Duration
+}
+
+// NewLineRenderer creates the line renderer that writes byte updates at most once per interval.
+func NewLineRenderer(w io.Writer, interval time.Duration) *LineRenderer {
+	return &LineRenderer{w: w, interval: interval}
+}
+
+func (r *LineRenderer) Report(p Progress) {
+	changed := p.Index != r.last.Index || p.Package != r.last.Package || p.Phase != r.last.Phase
+	if !changed && p.Elapsed-r.written < r.interval {
+		return
+	}
+	r.last, r.written = p, p.Elapsed
+	_, _ = fmt.Fprintln(r.w, progressLine(p))
+}
+
+// Finish does nothing since every line is complete. It resets the renderer for the next run.
+func (r *LineRenderer) Finish() {
+	r.last, r.written = Progress{}, 0
+}
+
+// BarRenderer redraws the progress bar in place on a terminal.
+type BarRenderer struct {
+	w     io.Writer
+	width int
+	drawn bool
+}
+
+// NewBarRenderer creates the bar renderer with the bar of the given width in characters.
+func NewBarRenderer(w io.Writer, width int) *BarRenderer {
+	return &BarRenderer{w: w, width: width}
+}
+
+func (r *BarRenderer) Report(p Progress) {
+	done := 0
+	if p.Total > 0 {
+		// The current package is half-done.
+		done = (2*(p.Index-1) + 1) * r.width / (2 * p.Total)
+	}
+	bar := strings.Repeat("=", done) + ">" + strings.Repeat(" ", r.width-done)
+	// Carriage return and erasing the line redraw the bar in place.
+	_, _ = fmt.Fprintf(r.w, "\r\033[K[%s] %s", bar[:r.width], progressLine(p))
+	r.drawn = true
+}
+
+// Finish moves the cursor below the bar, so that the following output doesn't overwrite it.
+func (r *BarRenderer) Finish() {
+	if r.drawn {
+		_, _ = fmt.Fprintln(r.w)
+	}
+	r.drawn = false
+}
@@ -0,0 +1,85 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// progressRecorder records the reported progress without the elapsed time.
+type progressRecorder struct {
+	reports []Progress
+}
+
+func (r *progressRecorder) Report(p Progress) {
+	p.Elapsed = 0
+	r.reports = append(r.reports, p)
+}
+
+func TestFix_Progress(t *testing.T) {
+	b, err := newFakeBundle(&fakeManager{}, "chrony/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb")
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	r := &progressRecorder{}
+	if _, err = Fix(context.Background(), b, FixOptions{Progress: r}); err != nil {
+		t.Fatalf("Fix() error = %v", err)
+	}
+	want := []Progress{{Index: 1, Total: 2, Package: "chrony"}, {Index: 2, Total: 2, Package: "ntp"}}
+	if !reflect.DeepEqual(r.reports, want) {
+		t.Errorf("Fix() reported %v, want %v", r.reports, want)
+	}
+}
+
+func TestProgressTracker(t *testing.T) {
+	r := &progressRecorder{}
+	tracker := ProgressFrom(WithProgress(context.Background(), r))
+	tracker.StartPackage(3, 25, "kubelet")
+	tracker.SetPhase(PhaseDownload)
+	tracker.SetBytes(1024)
+	tracker.SetPhase(PhaseCopy)
+	tracker.AddBytes(512)
+	tracker.AddBytes(512)
+	want := []Progress{
+		{Index: 3, Total: 25, Package: "kubelet"},
+		{Index: 3, Total: 25, Package: "kubelet", Phase: PhaseDownload},
+		{Index: 3, Total: 25, Package: "kubelet", Phase: PhaseDownload, Bytes: 1024},
+		{Index: 3, Total: 25, Package: "kubelet", Phase: PhaseCopy},
+		{Index: 3, Total: 25, Package: "kubelet", Phase: PhaseCopy, Bytes: 512},
+		{Index: 3, Total: 25, Package: "kubelet", Phase: PhaseCopy, Bytes: 1024},
+	}
+	if !reflect.DeepEqual(r.reports, want) {
+		t.Errorf("ProgressTracker reported %v, want %v", r.reports, want)
+	}
+	// A context without a tracker gives a nil tracker that ignores the calls.
+	ProgressFrom(context.Background()).SetPhase(PhaseSimulate)
+}
+
+func TestLineRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewLineRenderer(&buf, 10*time.Second)
+	p := Progress{Index: 3, Total: 25, Package: "kubelet", Phase: PhaseDownload, Elapsed: 65 * time.Second}
+	r.Report(p)
+	p.Bytes, p.Elapsed = 1024, 66*time.Second
+	r.Report(p)
+	p.Bytes, p.Elapsed = 12*1024*1024+300*1024, 76*time.Second
+	r.Report(p)
+	want := "[3/25] kubelet: download, 1m5s elapsed\n" +
+		"[3/25] kubelet: download 12.3 MiB, 1m16s elapsed\n"
+	if buf.String() != want {
+		t.Errorf("LineRenderer wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestBarRenderer(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewBarRenderer(&buf, 10)
+	r.Report(Progress{Index: 2, Total: 4, Package: "ntp", Phase: PhaseSimulate, Elapsed: time.Second})
+	r.Finish()
+	want := "\r\033[K[===>      ] [2/4] ntp: simulate, 1s elapsed\n"
+	if buf.String() != want {
+		t.Errorf("BarRenderer wrote %q, want %q", buf.String(), want)
+	}
+}
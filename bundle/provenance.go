@@ -0,0 +1,171 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+	slsaProvenanceType  = "https://slsa.dev/provenance/v1"
+	// provenanceBuildType describes how the tool builds the bundle.
+	provenanceBuildType = "urn:konvoy-os-package-builder:fix-bundle:v1"
+)
+
+// Provenance describes how the bundle was built: the inputs, the build environment and the output files.
+type Provenance struct {
+	ToolVersion string
+	// HostRelease is the OS release of the host that built the bundle.
+	HostRelease  string
+	Architecture string
+	// Sources are the package manager sources the packages were resolved and downloaded with.
+	Sources []string
+	// Parameters are the options of the run, for example the policy file.
+	Parameters map[string]string
+	// Materials are the input files of the run, for example the original bundle tarball.
+	Materials []ProvenanceFile
+	// Subjects are the output package files with their origins and the output tarball.
+	Subjects []ProvenanceFile
+	Started  time.Time
+	Finished time.Time
+}
+
+// ProvenanceFile is an input or an output file of the run. Origin is set for the package files only.
+type ProvenanceFile struct {
+	Name   string
+	SHA256 string
+	Origin string
+}
+
+// NewProvenance creates the provenance with the package files of the bundle as subjects.
+func NewProvenance(b *Bundle) (*Provenance, error) {
+	p := &Provenance{Architecture: b.Architecture, Parameters: make(map[string]string)}
+	for _, f := range b.Files() {
+		_, sum, err := f.Package.Digest()
+		if err != nil {
+			return nil, err
+		}
+		p.Subjects = append(p.Subjects, ProvenanceFile{Name: f.Path, SHA256: sum, Origin: f.Package.Origin.String()})
+	}
+	return p, nil
+}
+
+// AddMaterial adds the input file with its digest.
+func (p *Provenance) AddMaterial(name string, r io.Reader) error {
+	sum, err := digest(r)
+	if err != nil {
+		return fmt.Errorf("cannot calculate digest of %s. Error: %w", name, err)
+	}
+	p.Materials = append(p.Materials, ProvenanceFile{Name: name, SHA256: sum})
+	return nil
+}
+
+// AddSubject adds the output file with its digest.
+func (p *Provenance) AddSubject(name string, r io.Reader) error {
+	sum, err := digest(r)
+	if err != nil {
+		return fmt.Errorf("cannot calculate digest of %s. Error: %w", name, err)
+	}
+	p.Subjects = append(p.Subjects, ProvenanceFile{Name: name, SHA256: sum})
+	return nil
+}
+
+func digest(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type inTotoStatement struct {
+	Type          string                  `json:"_type"`
+	Subject       []inTotoResource        `json:"subject"`
+	PredicateType string                  `json:"predicateType"`
+	Predicate     slsaProvenancePredicate `json:"predicate"`
+}
+
+type inTotoResource struct {
+	Name        string            `json:"name"`
+	Digest      map[string]string `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type slsaProvenancePredicate struct {
+	BuildDefinition slsaBuildDefinition `json:"buildDefinition"`
+	RunDetails      slsaRunDetails      `json:"runDetails"`
+}
+
+type slsaBuildDefinition struct {
+	BuildType            string            `json:"buildType"`
+	ExternalParameters   map[string]string `json:"externalParameters"`
+	InternalParameters   slsaEnvironment   `json:"internalParameters"`
+	ResolvedDependencies []inTotoResource  `json:"resolvedDependencies"`
+}
+
+type slsaEnvironment struct {
+	HostRelease  string   `json:"hostRelease"`
+	Architecture string   `json:"architecture"`
+	Sources      []string `json:"sources"`
+}
+
+type slsaRunDetails struct {
+	Builder  slsaBuilder  `json:"builder"`
+	Metadata slsaMetadata `json:"metadata"`
+}
+
+type slsaBuilder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version"`
+}
+
+type slsaMetadata struct {
+	StartedOn  string `json:"startedOn"`
+	FinishedOn string `json:"finishedOn"`
+}
+
+// WriteInToto writes the provenance as an in-toto statement with an SLSA provenance predicate.
+func (p *Provenance) WriteInToto(w io.Writer) error {
+	statement := inTotoStatement{
+		Type:          inTotoStatementType,
+		Subject:       provenanceResources(p.Subjects),
+		PredicateType: slsaProvenanceType,
+		Predicate: slsaProvenancePredicate{
+			BuildDefinition: slsaBuildDefinition{
+				BuildType:          provenanceBuildType,
+				ExternalParameters: p.Parameters,
+				InternalParameters: slsaEnvironment{
+					HostRelease:  p.HostRelease,
+					Architecture: p.Architecture,
+					Sources:      p.Sources,
+				},
+				ResolvedDependencies: provenanceResources(p.Materials),
+			},
+			RunDetails: slsaRunDetails{
+				Builder: slsaBuilder{
+					ID:      toolName,
+					Version: map[string]string{toolName: p.ToolVersion},
+				},
+				Metadata: slsaMetadata{
+					StartedOn:  p.Started.UTC().Format(time.RFC3339),
+					FinishedOn: p.Finished.UTC().Format(time.RFC3339),
+				},
+			},
+		},
+	}
+	return writeJSON(w, statement)
+}
+
+func provenanceResources(files []ProvenanceFile) []inTotoResource {
+	resources := make([]inTotoResource, len(files))
+	for i, f := range files {
+		resources[i] = inTotoResource{Name: f.Name, Digest: map[string]string{"sha256": f.SHA256}}
+		if f.Origin != "" {
+			resources[i].Annotations = map[string]string{"origin": f.Origin}
+		}
+	}
+	return resources
+}
@@ -0,0 +1,63 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvenance_WriteInToto(t *testing.T) {
+	b, err := newFakeBundle(&fakeManager{},
+		"chrony/chrony_3.2_amd64.deb",
+		"chrony/libseccomp_2.5_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	b.Packages[0].Dependencies[0].Origin = OriginDownloaded
+	p, err := NewProvenance(b)
+	if err != nil {
+		t.Fatalf("NewProvenance() error = %v", err)
+	}
+	p.ToolVersion = "v1.2.0"
+	p.HostRelease = "Ubuntu 18.04.6 LTS"
+	p.Sources = []string{"deb http://archive.ubuntu.com/ubuntu bionic main"}
+	p.Parameters["policy"] = "policy.json"
+	p.Started = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	p.Finished = p.Started.Add(time.Minute)
+	if err = p.AddMaterial("policy.json", strings.NewReader("{}")); err != nil {
+		t.Fatalf("AddMaterial() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err = p.WriteInToto(&buf); err != nil {
+		t.Fatalf("WriteInToto() error = %v", err)
+	}
+	var statement inTotoStatement
+	if err = json.Unmarshal(buf.Bytes(), &statement); err != nil {
+		t.Fatalf("WriteInToto() wrote invalid JSON: %v", err)
+	}
+	wantSubject := []inTotoResource{
+		{Name: "chrony/chrony_3.2_amd64.deb", Digest: map[string]string{"sha256": sha256Hex("chrony/chrony_3.2_amd64.deb")},
+			Annotations: map[string]string{"origin": "original"}},
+		{Name: "chrony/libseccomp_2.5_amd64.deb", Digest: map[string]string{
+			"sha256": sha256Hex("chrony/libseccomp_2.5_amd64.deb")}, Annotations: map[string]string{"origin": "downloaded"}},
+	}
+	if !reflect.DeepEqual(statement.Subject, wantSubject) {
+		t.Errorf("WriteInToto() subject = %v, want %v", statement.Subject, wantSubject)
+	}
+	wantMaterials := []inTotoResource{{Name: "policy.json", Digest: map[string]string{"sha256": sha256Hex("{}")}}}
+	if got := statement.Predicate.BuildDefinition.ResolvedDependencies; !reflect.DeepEqual(got, wantMaterials) {
+		t.Errorf("WriteInToto() resolved dependencies = %v, want %v", got, wantMaterials)
+	}
+	if got := statement.Predicate.RunDetails.Builder.Version[toolName]; got != "v1.2.0" {
+		t.Errorf("WriteInToto() builder version = %s, want v1.2.0", got)
+	}
+}
+
+func sha256Hex(s string) string {
+	sum, _ := digest(strings.NewReader(s))
+	return sum
+}
@@ -0,0 +1,61 @@
+package bundle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PrunedDependency is a dependency removed from the bundle because the base image already has it.
+type PrunedDependency struct {
+	Package          *Package
+	Dependency       *Package
+	InstalledVersion string
+	Size             int64
+}
+
+type PruneResult struct {
+	Pruned     []PrunedDependency
+	SavedBytes int64
+}
+
+// PruneDependencies drops the package dependencies that the base image already has at the same or a newer
+// version. Installed maps the names of the base image packages to their versions. Main packages are never pruned.
+func PruneDependencies(b *Bundle, installed map[string]string) (*PruneResult, error) {
+	res := &PruneResult{}
+	for _, p := range b.Packages {
+		dependencies := make([]*Package, 0, len(p.Dependencies))
+		for _, d := range p.Dependencies {
+			v, ok := installed[d.Name]
+			if !ok || b.Manager.CompareVersions(v, d.Version) < 0 {
+				dependencies = append(dependencies, d)
+				continue
+			}
+			info, err := d.Stat()
+			if err != nil {
+				return nil, fmt.Errorf("cannot stat dependency %s. Error: %w", d.Path, err)
+			}
+			res.Pruned = append(res.Pruned, PrunedDependency{
+				Package:          p,
+				Dependency:       d,
+				InstalledVersion: v,
+				Size:             info.Size(),
+			})
+			res.SavedBytes += info.Size()
+		}
+		p.Dependencies = dependencies
+	}
+	return res, nil
+}
+
+func (r *PruneResult) String() string {
+	if len(r.Pruned) == 0 {
+		return "No dependencies were pruned: the base image has none of them."
+	}
+	lines := make([]string, len(r.Pruned))
+	for i, pd := range r.Pruned {
+		lines[i] = fmt.Sprintf("%s: %s - v%s (v%s is installed)", pd.Package.Name, pd.Dependency.Name,
+			pd.Dependency.Version, pd.InstalledVersion)
+	}
+	return fmt.Sprintf("The following dependencies were pruned because the base image already has them:\n%s\n"+
+		"Saved %d bytes.", strings.Join(lines, "\n"), r.SavedBytes)
+}
@@ -0,0 +1,56 @@
+package bundle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPruneDependencies(t *testing.T) {
+	m := &fakeManager{}
+	b, err := newSizedFakeBundle(m, map[string]int{
+		"chrony/chrony_3.2_amd64.deb":      100,
+		"chrony/libseccomp2_2.5_amd64.deb": 20,
+		"chrony/libcap_2.2_amd64.deb":      30,
+		"chrony/tzdata_2021_all.deb":       40,
+		"ntp/ntp_4.2_amd64.deb":            100,
+		"ntp/libcap_2.2_amd64.deb":         30,
+	})
+	if err != nil {
+		t.Fatalf("newSizedFakeBundle() error = %v", err)
+	}
+	installed := map[string]string{
+		// Newer and the same versions are pruned.
+		"libseccomp2": "2.6",
+		"tzdata":      "2021",
+		// Older versions are kept.
+		"libcap": "2.1",
+		// Main packages are never pruned.
+		"ntp": "4.2",
+	}
+	res, err := PruneDependencies(b, installed)
+	if err != nil {
+		t.Fatalf("PruneDependencies() error = %v", err)
+	}
+	pruned := make([]string, len(res.Pruned))
+	for i, pd := range res.Pruned {
+		pruned[i] = pd.Package.Name + ": " + pd.Dependency.Name + " " + pd.InstalledVersion
+	}
+	if want := []string{"chrony: libseccomp2 2.6", "chrony: tzdata 2021"}; !reflect.DeepEqual(pruned, want) {
+		t.Errorf("PruneDependencies() pruned = %v, want %v", pruned, want)
+	}
+	if res.SavedBytes != 60 {
+		t.Errorf("PruneDependencies() saved bytes = %d, want 60", res.SavedBytes)
+	}
+	dependencies := make(map[string][]string)
+	for _, p := range b.Packages {
+		for _, d := range p.Dependencies {
+			dependencies[p.Name] = append(dependencies[p.Name], d.Name)
+		}
+	}
+	if want := map[string][]string{"chrony": {"libcap"}, "ntp": {"libcap"}}; !reflect.DeepEqual(dependencies, want) {
+		t.Errorf("PruneDependencies() left dependencies %v, want %v", dependencies, want)
+	}
+	if len(b.Packages) != 2 {
+		t.Errorf("PruneDependencies() left %d packages, want 2", len(b.Packages))
+	}
+}
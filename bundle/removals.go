@@ -0,0 +1,32 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+)
+
+// explainRemovals explains why installation of the package would remove every node package.
+func explainRemovals(ctx context.Context, p *Package, b *Bundle, removals []Change) []string {
+	reasons := make([]string, len(removals))
+	for i, c := range removals {
+		reasons[i] = fmt.Sprintf("%s - v%s: %s", c.Name, c.OldVersion, removalReason(ctx, p, b, c))
+	}
+	return reasons
+}
+
+func removalReason(ctx context.Context, p *Package, b *Bundle, c Change) string {
+	removed := &Package{NameVersion: NameVersion{Name: c.Name, Version: c.OldVersion}}
+	for _, pp := range append([]*Package{p}, p.Dependencies...) {
+		control, err := pp.Control(ctx)
+		if err != nil {
+			return "the reason is unknown because " + err.Error()
+		}
+		if r, ok := findRelation(b.Manager, control.Conflicts, removed, &Control{}); ok {
+			return fmt.Sprintf("%s - v%s conflicts with it (Conflicts: %s)", pp.Name, pp.Version, r)
+		}
+		if r, ok := findRelation(b.Manager, control.Breaks, removed, &Control{}); ok {
+			return fmt.Sprintf("%s - v%s breaks it (Breaks: %s)", pp.Name, pp.Version, r)
+		}
+	}
+	return "the package manager removes it to satisfy the dependencies of the installed packages"
+}
@@ -0,0 +1,225 @@
+package bundle
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+// Report is the summary of a fix run for people who sign off the bundle.
+type Report struct {
+	Created           time.Time
+	Architecture      string
+	InitialTree       string
+	FixedTree         string
+	Actions           []ReportAction
+	Files             []ReportFile
+	Unresolved        []string
+	Incompatibilities []string
+	Vulnerabilities   []string
+	// Quarantined are the corrupt package files taken out of the bundle.
+	Quarantined []string
+	TotalSize   int64
+}
+
+// ReportAction is what was done with a main package of the bundle.
+type ReportAction struct {
+	Name       string
+	OldVersion string
+	NewVersion string
+	Action     string
+	Success    bool
+	// UnmetDependencies are all the unmet dependencies found while fixing the package.
+	UnmetDependencies []string
+	// Decisions are the decisions made about the proposed replacements.
+	Decisions  []string
+	Violations []string
+	Log        []string
+	// Outputs are the raw outputs of the simulated installations.
+	Outputs []string
+}
+
+// ReportFile is a package file of the fixed bundle.
+type ReportFile struct {
+	Path         string
+	Name         string
+	Version      string
+	Architecture string
+	Origin       PackageOrigin
+	Size         int64
+	SHA256       string
+}
+
+// NewReport creates the report from the fixed bundle and the result of fixing it.
+func NewReport(b *Bundle, res *BundleFixResult) (*Report, error) {
+	r := &Report{
+		Created:      time.Now().UTC(),
+		Architecture: b.Architecture,
+		InitialTree:  res.InitialTree,
+		FixedTree:    res.FixedTree,
+		Unresolved:   res.Unresolved,
+	}
+	for _, fr := range res.Results {
+		r.Actions = append(r.Actions, newReportAction(fr))
+	}
+	for _, i := range res.Incompatibilities {
+		r.Incompatibilities = append(r.Incompatibilities, i.String())
+	}
+	for _, v := range res.Vulnerabilities {
+		r.Vulnerabilities = append(r.Vulnerabilities, v.String())
+	}
+	for _, c := range b.Quarantine {
+		r.Quarantined = append(r.Quarantined, c.String())
+	}
+	for _, f := range b.Files() {
+		size, sum, err := f.Package.Digest()
+		if err != nil {
+			return nil, err
+		}
+		r.Files = append(r.Files, ReportFile{
+			Path:         f.Path,
+			Name:         f.Package.Name,
+			Version:      f.Package.Version,
+			Architecture: f.Package.Architecture,
+			Origin:       f.Package.Origin,
+			Size:         size,
+			SHA256:       sum,
+		})
+		r.TotalSize += size
+	}
+	return r, nil
+}
+
+func newReportAction(fr *FixResult) ReportAction {
+	a := ReportAction{Name: fr.Original.Name, OldVersion: fr.Original.Version, Success: fr.Success,
+		Violations: fr.Violations, Log: fr.Log}
+	switch {
+	case !fr.Success:
+		a.Action = "not fixed"
+	case fr.Package.Origin == OriginReplaced && fr.Package.Version != fr.Original.Version:
+		a.NewVersion = fr.Package.Version
+		a.Action = "replaced"
+	case fr.Package.Origin == OriginReplaced:
+		a.NewVersion = fr.Package.Version
+		a.Action = "downloaded again"
+	default:
+		a.NewVersion = fr.Package.Version
+		a.Action = "kept"
+	}
+	for _, d := range fr.Decisions {
+		a.Decisions = append(a.Decisions, d.String())
+	}
+	seen := make(map[string]bool)
+	for _, s := range fr.Simulations {
+		for _, ud := range s.UnmetDependencies {
+			d := printAlternatives(ud)
+			if !seen[d] {
+				seen[d] = true
+				a.UnmetDependencies = append(a.UnmetDependencies, d)
+			}
+		}
+		a.Outputs = append(a.Outputs, s.Output)
+	}
+	return a
+}
+
+// WriteHTML writes the report as a single HTML page without external assets.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"size": formatSize,
+	"inc":  func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Package bundle fix report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+pre { background: #f6f6f6; padding: 8px; overflow-x: auto; }
+.ok { color: #38761d; font-weight: bold; }
+.failed { color: #cc0000; font-weight: bold; }
+.mono { font-family: monospace; }
+.trees { display: flex; gap: 2em; }
+</style>
+</head>
+<body>
+<h1>Package bundle fix report</h1>
+<p>Created: {{.Created.Format "2006-01-02 15:04:05 MST"}}
+{{- if .Architecture}}. Architecture: {{.Architecture}}{{end}}.</p>
+{{if .Unresolved}}<p class="failed">The following packages were not fixed:
+{{- range $i, $n := .Unresolved}}{{if $i}},{{end}} {{$n}}{{end}}.</p>
+{{else}}<p class="ok">All packages were fixed.</p>
+{{end}}
+{{- if .Quarantined}}<p class="failed">The following package files failed the integrity verification and were
+quarantined:</p>
+<ul class="mono">{{range .Quarantined}}<li>{{.}}</li>{{end}}</ul>
+{{end}}
+<h2>Bundle trees</h2>
+<div class="trees">
+<div><h3>Initial</h3><pre>{{.InitialTree}}</pre></div>
+<div><h3>Fixed</h3><pre>{{.FixedTree}}</pre></div>
+</div>
+<h2>Actions</h2>
+<table>
+<tr><th>Package</th><th>Initial version</th><th>Fixed version</th><th>Action</th><th>Unmet dependencies</th>
+<th>Decisions</th><th>Policy violations</th></tr>
+{{range .Actions}}<tr>
+<td>{{.Name}}</td><td>{{.OldVersion}}</td><td>{{.NewVersion}}</td>
+<td class="{{if .Success}}ok{{else}}failed{{end}}">{{.Action}}</td>
+<td>{{range .UnmetDependencies}}{{.}}<br>{{end}}</td>
+<td>{{range .Decisions}}{{.}}<br>{{end}}</td>
+<td>{{range .Violations}}{{.}}<br>{{end}}</td>
+</tr>
+{{end}}</table>
+{{if .Incompatibilities}}<h2>Incompatibilities</h2>
+<ul>
+{{range .Incompatibilities}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}
+{{if .Vulnerabilities}}<h2>Known vulnerabilities</h2>
+<ul>
+{{range .Vulnerabilities}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}
+<h2>Files</h2>
+<p>{{len .Files}} files, {{size .TotalSize}} in total.</p>
+<table>
+<tr><th>File</th><th>Package</th><th>Version</th><th>Architecture</th><th>Origin</th><th>Size</th><th>SHA-256</th></tr>
+{{range .Files}}<tr>
+<td class="mono">{{.Path}}</td><td>{{.Name}}</td><td>{{.Version}}</td><td>{{.Architecture}}</td><td>{{.Origin}}</td>
+<td>{{size .Size}}</td><td class="mono">{{.SHA256}}</td>
+</tr>
+{{end}}</table>
+<h2>Details</h2>
+{{range .Actions}}<details>
+<summary>{{.Name}} - {{.Action}}</summary>
+<h3>Log</h3>
+<pre>{{range .Log}}{{.}}
+{{end}}</pre>
+{{range $i, $o := .Outputs}}<h3>APT output #{{inc $i}}</h3>
+<pre>{{$o}}</pre>
+{{end}}</details>
+{{end}}
+</body>
+</html>
+`))
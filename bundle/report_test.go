@@ -0,0 +1,67 @@
+package bundle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNewReport(t *testing.T) {
+	m := &fakeManager{}
+	b, err := newFakeBundle(m,
+		"chrony/chrony_3.2_amd64.deb",
+		"kubeadm=1.20.11/kubeadm_1.20.11_amd64.deb",
+		"kubeadm=1.20.11/cni_0.8.7_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	replaced := &Package{NameVersion: NameVersion{Name: "chrony", Version: "3.5"}, Origin: OriginReplaced}
+	res := &BundleFixResult{
+		Results: []*FixResult{
+			{Original: b.Packages[0], Package: replaced, Success: true, Simulations: []InstallResult{
+				{Result: ResultUnmetDependencies,
+					Output:            "chrony : Depends: libnss3 (>= 2:3.13.4) but it is not going to be installed",
+					UnmetDependencies: [][]Relation{{{Name: "libnss3", Operator: ">=", Version: "2:3.13.4"}}}},
+				{Result: ResultOk, Output: "Inst chrony (3.5 Ubuntu:18.04/bionic [amd64])"},
+			}},
+			{Original: b.Packages[1], Log: []string{"FAILED"}},
+		},
+		Unresolved:  []string{"kubeadm"},
+		InitialTree: "Initial package bundle",
+		FixedTree:   "Fixed package bundle",
+	}
+	r, err := NewReport(b, res)
+	if err != nil {
+		t.Fatalf("NewReport() error = %v", err)
+	}
+	if len(r.Files) != 3 || r.TotalSize != int64(len("chrony/chrony_3.2_amd64.deb")+
+		len("kubeadm=1.20.11/kubeadm_1.20.11_amd64.deb")+len("kubeadm=1.20.11/cni_0.8.7_amd64.deb")) {
+		t.Errorf("NewReport() files = %v, total size = %d", r.Files, r.TotalSize)
+	}
+	wantActions := []struct{ action, newVersion, unmet string }{
+		{"replaced", "3.5", "libnss3 (>= 2:3.13.4)"},
+		{"not fixed", "", ""},
+	}
+	for i, want := range wantActions {
+		a := r.Actions[i]
+		unmet := strings.Join(a.UnmetDependencies, "")
+		if a.Action != want.action || a.NewVersion != want.newVersion || unmet != want.unmet {
+			t.Errorf("NewReport() action #%d = %+v, want %+v", i, a, want)
+		}
+	}
+	var buf bytes.Buffer
+	if err = r.WriteHTML(&buf); err != nil {
+		t.Fatalf("WriteHTML() error = %v", err)
+	}
+	for _, want := range []string{
+		"The following packages were not fixed: kubeadm.",
+		"libnss3 (&gt;= 2:3.13.4)",
+		"Inst chrony (3.5 Ubuntu:18.04/bionic [amd64])",
+		"kubeadm=1.20.11/cni_0.8.7_amd64.deb",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteHTML() doesn't contain %q", want)
+		}
+	}
+}
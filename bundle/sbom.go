@@ -0,0 +1,359 @@
+package bundle
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SBOM is the software bill of materials of the bundle.
+type SBOM struct {
+	Name       string
+	Created    time.Time
+	Components []SBOMComponent
+}
+
+// SBOMComponent is a package of the bundle. The same package in several package directories is one component.
+type SBOMComponent struct {
+	ID           string
+	Name         string
+	Version      string
+	Architecture string
+	Maintainer   string
+	// Source is the source package name and version, for example "openssl 1.1.1-1ubuntu2.1~18.04.13".
+	Source string
+	// Vendor is the distro the package comes from, for example "ubuntu". Empty means that it's unknown.
+	Vendor string
+	// Licenses are SPDX license expressions of the parts of the package. Empty means that the licenses are unknown.
+	Licenses []string
+	SHA256   string
+	// Paths are the paths of the package file in the bundle tarball.
+	Paths []string
+	// DependsOn are the IDs of the components the package depends on.
+	DependsOn []string
+}
+
+// NewSBOM describes every package file of the bundle with its dependencies on the other bundle packages.
+// The vendor is the distro of the package sources, for example "ubuntu". Empty means that it's unknown.
+func NewSBOM(ctx context.Context, b *Bundle, name, vendor string) (*SBOM, error) {
+	s := &SBOM{Name: name, Created: time.Now().UTC()}
+	g, err := NewGraph(ctx, b, nil)
+	if err != nil {
+		return nil, err
+	}
+	components := make(map[string]*SBOMComponent, len(g.Nodes))
+	ids := make(map[NameVersion]string, len(g.Nodes))
+	for _, n := range g.Nodes {
+		c, err := newSBOMComponent(ctx, b, n.Package)
+		if err != nil {
+			return nil, err
+		}
+		c.ID = "Package-" + n.ID
+		c.Vendor = vendor
+		components[n.ID] = c
+		ids[n.Package.NameVersion] = n.ID
+	}
+	for _, e := range g.Edges {
+		c := components[e.From]
+		if dependsOn := "Package-" + e.To; !contains(c.DependsOn, dependsOn) {
+			c.DependsOn = append(c.DependsOn, dependsOn)
+		}
+	}
+	for _, f := range b.Files() {
+		c := components[ids[f.Package.NameVersion]]
+		c.Paths = append(c.Paths, f.Path)
+	}
+	for _, n := range g.Nodes {
+		s.Components = append(s.Components, *components[n.ID])
+	}
+	return s, nil
+}
+
+func newSBOMComponent(ctx context.Context, b *Bundle, p *Package) (*SBOMComponent, error) {
+	control, err := p.Control(ctx)
+	if err != nil {
+		return nil, err
+	}
+	licenses, err := b.Manager.ReadLicenses(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read licenses of package %s. Error: %w", p.Path, err)
+	}
+	_, sum, err := p.Digest()
+	if err != nil {
+		return nil, err
+	}
+	c := &SBOMComponent{
+		Name:         p.Name,
+		Version:      p.Version,
+		Architecture: p.Architecture,
+		Maintainer:   control.Maintainer,
+		Licenses:     licenses,
+		SHA256:       sum,
+	}
+	if control.Source != "" {
+		c.Source = strings.TrimSpace(control.Source + " " + control.SourceVersion)
+	}
+	return c, nil
+}
+
+// LicenseExpression returns the SPDX license expression of the component or NOASSERTION if it's unknown.
+func (c SBOMComponent) LicenseExpression() string {
+	if len(c.Licenses) == 0 {
+		return "NOASSERTION"
+	}
+	if len(c.Licenses) == 1 {
+		return c.Licenses[0]
+	}
+	expressions := make([]string, len(c.Licenses))
+	for i, l := range c.Licenses {
+		expressions[i] = l
+		// AND binds tighter than OR, so the alternatives of a part keep their meaning in parentheses.
+		if strings.Contains(l, " OR ") {
+			expressions[i] = "(" + l + ")"
+		}
+	}
+	return strings.Join(expressions, " AND ")
+}
+
+// PURL returns the package URL of the component. The vendor is its namespace.
+func (c SBOMComponent) PURL() string {
+	purl := "pkg:deb/"
+	if c.Vendor != "" {
+		purl += url.PathEscape(c.Vendor) + "/"
+	}
+	purl += fmt.Sprintf("%s@%s", url.PathEscape(c.Name), url.PathEscape(c.Version))
+	if c.Architecture != "" {
+		purl += "?arch=" + url.QueryEscape(c.Architecture)
+	}
+	return purl
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo"`
+	Supplier         string            `json:"supplier"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	SourceInfo       string            `json:"sourceInfo,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// WriteSPDX writes the SBOM as an SPDX 2.3 JSON document.
+func (s *SBOM) WriteSPDX(w io.Writer) error {
+	serial, err := newUUID()
+	if err != nil {
+		return err
+	}
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              s.Name,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/%s-%s", url.PathEscape(s.Name), serial),
+		CreationInfo: spdxCreationInfo{
+			Created:  s.Created.Format(time.RFC3339),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages:      make([]spdxPackage, 0, len(s.Components)),
+		Relationships: make([]spdxRelationship, 0),
+	}
+	for _, c := range s.Components {
+		supplier := "NOASSERTION"
+		if c.Maintainer != "" {
+			supplier = "Organization: " + c.Maintainer
+		}
+		p := spdxPackage{
+			Name:             c.Name,
+			SPDXID:           "SPDXRef-" + c.ID,
+			VersionInfo:      c.Version,
+			Supplier:         supplier,
+			DownloadLocation: "NOASSERTION",
+			Checksums:        []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: c.SHA256}},
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  c.LicenseExpression(),
+			CopyrightText:    "NOASSERTION",
+			Comment:          fmt.Sprintf("Architecture: %s. Bundle paths: %s.", c.Architecture, strings.Join(c.Paths, ", ")),
+			ExternalRefs: []spdxExternalRef{
+				{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.PURL()},
+			},
+		}
+		if c.Source != "" {
+			p.SourceInfo = "built package from: " + c.Source
+		}
+		doc.Packages = append(doc.Packages, p)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID: doc.SPDXID, RelationshipType: "DESCRIBES", RelatedSPDXElement: p.SPDXID,
+		})
+		for _, d := range c.DependsOn {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID: p.SPDXID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-" + d,
+			})
+		}
+	}
+	return writeJSON(w, doc)
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Supplier   *cycloneDXSupplier  `json:"supplier,omitempty"`
+	Hashes     []cycloneDXHash     `json:"hashes,omitempty"`
+	Licenses   []cycloneDXLicense  `json:"licenses,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXSupplier struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// WriteCycloneDX writes the SBOM as a CycloneDX 1.4 JSON document.
+func (s *SBOM) WriteCycloneDX(w io.Writer) error {
+	serial, err := newUUID()
+	if err != nil {
+		return err
+	}
+	doc := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: "urn:uuid:" + serial,
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: s.Created.Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Name: toolName}},
+			Component: cycloneDXComponent{Type: "application", BOMRef: "bundle", Name: s.Name},
+		},
+		Components:   make([]cycloneDXComponent, 0, len(s.Components)),
+		Dependencies: make([]cycloneDXDependency, 0, len(s.Components)+1),
+	}
+	bundleDependency := cycloneDXDependency{Ref: "bundle", DependsOn: make([]string, 0, len(s.Components))}
+	for _, c := range s.Components {
+		component := cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  c.ID,
+			Name:    c.Name,
+			Version: c.Version,
+			Hashes:  []cycloneDXHash{{Alg: "SHA-256", Content: c.SHA256}},
+			PURL:    c.PURL(),
+			Properties: []cycloneDXProperty{
+				{Name: "deb:architecture", Value: c.Architecture},
+				{Name: "deb:source", Value: c.Source},
+				{Name: "bundle:paths", Value: strings.Join(c.Paths, ",")},
+			},
+		}
+		if c.Maintainer != "" {
+			component.Supplier = &cycloneDXSupplier{Name: c.Maintainer}
+		}
+		if len(c.Licenses) > 0 {
+			component.Licenses = []cycloneDXLicense{{Expression: c.LicenseExpression()}}
+		}
+		doc.Components = append(doc.Components, component)
+		dependsOn := append(make([]string, 0, len(c.DependsOn)), c.DependsOn...)
+		doc.Dependencies = append(doc.Dependencies, cycloneDXDependency{Ref: c.ID, DependsOn: dependsOn})
+		bundleDependency.DependsOn = append(bundleDependency.DependsOn, c.ID)
+	}
+	doc.Dependencies = append([]cycloneDXDependency{bundleDependency}, doc.Dependencies...)
+	return writeJSON(w, doc)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(v)
+}
+
+// newUUID returns a random UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate UUID. Error: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,77 @@
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNewSBOM(t *testing.T) {
+	m := &fakeManager{
+		controls: map[string]*Control{
+			"chrony":     {Maintainer: "Ubuntu Developers", Source: "chrony", SourceVersion: "3.2"},
+			"libseccomp": {Depends: [][]Relation{{{Name: "libc6"}}}},
+		},
+		licenses: map[string][]string{"chrony": {"GPL-2.0-only", "GPL-2.0-or-later OR BSD-3-Clause"}},
+	}
+	m.controls["chrony"].Depends = [][]Relation{{{Name: "libseccomp", Operator: ">=", Version: "2.1"}}}
+	b, err := newFakeBundle(m,
+		"chrony/chrony_3.2_amd64.deb",
+		"chrony/libseccomp_2.5_amd64.deb",
+		"ntpdate/ntpdate_4.2_amd64.deb",
+		"ntpdate/libseccomp_2.5_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	s, err := NewSBOM(context.Background(), b, "bundle.tar.gz", "ubuntu")
+	if err != nil {
+		t.Fatalf("NewSBOM() error = %v", err)
+	}
+	if len(s.Components) != 3 {
+		t.Fatalf("NewSBOM() components = %v, want chrony, libseccomp and ntpdate", s.Components)
+	}
+	chrony, libseccomp := s.Components[0], s.Components[1]
+	wantLicense := "GPL-2.0-only AND (GPL-2.0-or-later OR BSD-3-Clause)"
+	if chrony.Source != "chrony 3.2" || chrony.LicenseExpression() != wantLicense ||
+		!reflect.DeepEqual(chrony.DependsOn, []string{libseccomp.ID}) {
+		t.Errorf("NewSBOM() chrony = %+v", chrony)
+	}
+	wantPaths := []string{"chrony/libseccomp_2.5_amd64.deb", "ntpdate/libseccomp_2.5_amd64.deb"}
+	if !reflect.DeepEqual(libseccomp.Paths, wantPaths) || libseccomp.LicenseExpression() != "NOASSERTION" {
+		t.Errorf("NewSBOM() libseccomp = %+v", libseccomp)
+	}
+	if got, want := chrony.PURL(), "pkg:deb/ubuntu/chrony@3.2?arch=amd64"; got != want {
+		t.Errorf("PURL() = %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err = s.WriteSPDX(&buf); err != nil {
+		t.Fatalf("WriteSPDX() error = %v", err)
+	}
+	var spdx spdxDocument
+	if err = json.Unmarshal(buf.Bytes(), &spdx); err != nil {
+		t.Fatalf("WriteSPDX() wrote invalid JSON: %v", err)
+	}
+	dependsOn := spdxRelationship{SPDXElementID: "SPDXRef-" + chrony.ID, RelationshipType: "DEPENDS_ON",
+		RelatedSPDXElement: "SPDXRef-" + libseccomp.ID}
+	if len(spdx.Packages) != 3 || len(spdx.Relationships) != 4 || spdx.Relationships[1] != dependsOn {
+		t.Errorf("WriteSPDX() packages = %v, relationships = %v", spdx.Packages, spdx.Relationships)
+	}
+
+	buf.Reset()
+	if err = s.WriteCycloneDX(&buf); err != nil {
+		t.Fatalf("WriteCycloneDX() error = %v", err)
+	}
+	var cycloneDX cycloneDXDocument
+	if err = json.Unmarshal(buf.Bytes(), &cycloneDX); err != nil {
+		t.Fatalf("WriteCycloneDX() wrote invalid JSON: %v", err)
+	}
+	if len(cycloneDX.Components) != 3 || len(cycloneDX.Dependencies) != 4 ||
+		!reflect.DeepEqual(cycloneDX.Dependencies[1].DependsOn, []string{libseccomp.ID}) {
+		t.Errorf("WriteCycloneDX() components = %v, dependencies = %v", cycloneDX.Components,
+			cycloneDX.Dependencies)
+	}
+}
@@ -0,0 +1,219 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	// maxSearchDepth limits how many dependencies deep the search pins versions.
+	maxSearchDepth = 2
+	// maxDependencyCandidates limits how many versions of each unmet dependency are tried.
+	maxDependencyCandidates = 5
+	// maxTriedSets limits the number of simulated installations during one search.
+	maxTriedSets = 50
+)
+
+// SearchVersions searches the available versions of the package from the newest to the oldest until it finds
+// an installable set. When a version has unmet dependencies, it also tries combinations with the available
+// versions of those dependencies.
+func SearchVersions(ctx context.Context, p *Package, b *Bundle, res *FixResult) (*FixResult, error) {
+	return searchVersions(ctx, p, b, res, false)
+}
+
+// searchVersions searches the available versions of the package. When the solver prefers fixed versions, it tries
+// the versions not affected by the advisories first, or only them if onlyFixed is true.
+func searchVersions(ctx context.Context, p *Package, b *Bundle, res *FixResult, onlyFixed bool) (*FixResult, error) {
+	res.AddLog("I'm going to search for an installable version of the package among the available ones.")
+	versions, err := b.Manager.ListVersions(ctx, p.Name)
+	if err != nil {
+		res.AddLog("Couldn't list available versions of the package due to the following error: " + err.Error())
+		return res, err
+	}
+	if len(versions) == 0 {
+		res.AddLog("There are no available versions of the package. Please, check the package sources.")
+		return res, nil
+	}
+	res.AddLog(fmt.Sprintf("Available versions of the package: %s.", strings.Join(versions, ", ")))
+	allowed := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if b.Policy.AllowsBump(p.Version, v) {
+			allowed = append(allowed, v)
+		}
+	}
+	if len(allowed) < len(versions) {
+		res.AddLog(fmt.Sprintf("The policy allows a %s version bump at most, so I'm going to try only the "+
+			"following versions: %s.", b.Policy.MaxVersionBump, strings.Join(allowed, ", ")))
+		if len(allowed) == 0 {
+			res.addViolation(fmt.Sprintf("all available versions of %s exceed the %s version bump the policy allows",
+				p.Name, b.Policy.MaxVersionBump))
+			return res, nil
+		}
+		versions = allowed
+	}
+	if b.PreferFixedVersions && len(b.Advisories) > 0 {
+		fixed, affected, err := fixedVersions(ctx, b, p, versions)
+		if err != nil {
+			res.AddLog("Couldn't match the versions against the security advisories due to the following error: " +
+				err.Error())
+			return res, err
+		}
+		switch {
+		case onlyFixed && len(fixed) == 0:
+			res.AddLog("All available versions of the package are affected by known vulnerabilities.")
+			return res, nil
+		case onlyFixed:
+			versions = fixed
+			res.AddLog(fmt.Sprintf("I'm going to try only the versions without known vulnerabilities: %s.",
+				strings.Join(versions, ", ")))
+		default:
+			versions = append(fixed, affected...)
+			res.AddLog(fmt.Sprintf("I'm going to try the versions without known vulnerabilities first: %s.",
+				strings.Join(versions, ", ")))
+		}
+	}
+	s := &versionSearch{manager: b.Manager, policy: b.Policy, res: res, versions: map[string][]string{p.Name: versions}}
+	for _, v := range versions {
+		set, err := s.search(ctx, []NameVersion{{Name: p.Name, Version: v}}, maxSearchDepth)
+		if err != nil {
+			res.AddLog("Couldn't check if it's possible to install the package due to the following error: " +
+				err.Error())
+			return res, err
+		}
+		if set == nil {
+			if len(s.tried) >= maxTriedSets {
+				res.AddLog(fmt.Sprintf("I tried %d sets of packages and I'm going to stop searching.", len(s.tried)))
+				break
+			}
+			continue
+		}
+		res.AddLog(fmt.Sprintf("It is possible to install %s. I'm going to download the packages and "+
+			"their dependencies.", formatSet(set)))
+		newPackage, err := b.Manager.DownloadSet(ctx, path.Base(path.Dir(p.Path)), set)
+		if err != nil {
+			res.AddLog("Couldn't download the packages or their dependencies due to the following error: " +
+				err.Error())
+			return res, err
+		}
+		newPackage.Origin = OriginReplaced
+		return approveReplacement(ctx, p, newPackage, b, res)
+	}
+	tried := make([]string, len(s.tried))
+	for i, set := range s.tried {
+		tried[i] = formatSet(set)
+	}
+	res.AddLog("I couldn't find an installable version of the package. I tried the following sets of packages:\n" +
+		strings.Join(tried, "\n") + "\nPlease, contact support and provide them this output.")
+	return res, nil
+}
+
+type versionSearch struct {
+	manager  PackageManager
+	policy   Policy
+	res      *FixResult
+	versions map[string][]string
+	tried    [][]NameVersion
+}
+
+// search checks if the set is installable. When it's not because of unmet dependencies, it pins the available
+// versions of the first unmet dependency alternatives one by one and searches deeper. It returns nil when
+// nothing works.
+func (s *versionSearch) search(ctx context.Context, set []NameVersion, depth int) ([]NameVersion, error) {
+	if len(s.tried) >= maxTriedSets {
+		return nil, nil
+	}
+	r, err := s.manager.CheckInstallSet(ctx, set)
+	if err != nil {
+		return nil, err
+	}
+	s.tried = append(s.tried, set)
+	if r.Result == ResultOk {
+		removals := s.policy.DisallowedRemovals(r.Transaction)
+		if len(removals) == 0 {
+			return set, nil
+		}
+		names := make([]string, len(removals))
+		for i, c := range removals {
+			names[i] = c.Name
+		}
+		s.res.AddLog(fmt.Sprintf("Installation of %s would remove the following node packages, which the policy "+
+			"doesn't allow: %s.", formatSet(set), strings.Join(names, ", ")))
+		return nil, nil
+	}
+	s.res.AddLog(fmt.Sprintf("It's not possible to install %s.", formatSet(set)))
+	if r.Result != ResultUnmetDependencies || depth == 0 {
+		return nil, nil
+	}
+	for _, ud := range r.UnmetDependencies {
+		pinned := false
+		for _, alternative := range ud {
+			if containsName(set, alternative.Name) {
+				continue
+			}
+			pinned = true
+			candidates, err := s.candidates(ctx, alternative)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range candidates {
+				next := make([]NameVersion, len(set), len(set)+1)
+				copy(next, set)
+				next = append(next, NameVersion{Name: alternative.Name, Version: c})
+				found, err := s.search(ctx, next, depth-1)
+				if err != nil || found != nil {
+					return found, err
+				}
+			}
+		}
+		if pinned {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// candidates returns the newest available versions of the dependency that satisfy its version constraint.
+func (s *versionSearch) candidates(ctx context.Context, d Relation) ([]string, error) {
+	versions, ok := s.versions[d.Name]
+	if !ok {
+		var err error
+		versions, err = s.manager.ListVersions(ctx, d.Name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list versions of dependency %s. Error: %w", d.Name, err)
+		}
+		s.versions[d.Name] = versions
+	}
+	candidates := make([]string, 0, maxDependencyCandidates)
+	for _, v := range versions {
+		if !Satisfies(s.manager, d, v) {
+			continue
+		}
+		candidates = append(candidates, v)
+		if len(candidates) == maxDependencyCandidates {
+			break
+		}
+	}
+	return candidates, nil
+}
+
+func containsName(set []NameVersion, name string) bool {
+	for _, nv := range set {
+		if nv.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func formatSet(set []NameVersion) string {
+	parts := make([]string, len(set))
+	for i, nv := range set {
+		parts[i] = nv.Name
+		if nv.Version != "" {
+			parts[i] += "=" + nv.Version
+		}
+	}
+	return strings.Join(parts, " ")
+}
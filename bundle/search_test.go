@@ -0,0 +1,139 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchVersions(t *testing.T) {
+	ok := InstallResult{Result: ResultOk}
+	unmet := func(alternatives ...Relation) InstallResult {
+		return InstallResult{Result: ResultUnmetDependencies, UnmetDependencies: [][]Relation{alternatives}}
+	}
+	manyVersions := make([]string, 0, maxTriedSets+10)
+	for i := maxTriedSets + 10; i > 0; i-- {
+		manyVersions = append(manyVersions, fmt.Sprintf("3.%03d", i))
+	}
+	tests := []struct {
+		name     string
+		versions map[string][]string
+		installs map[string]InstallResult
+		policy   Policy
+		// wantChecked are the sets the search checks in order. Nil means any.
+		wantChecked []string
+		wantVersion string
+		wantLog     string
+	}{
+		{
+			name:        "picks the newest installable version",
+			versions:    map[string][]string{"chrony": {"3.5", "3.4", "3.3"}},
+			installs:    map[string]InstallResult{"chrony=3.4": ok, "chrony=3.3": ok},
+			wantChecked: []string{"chrony=3.5", "chrony=3.4"},
+			wantVersion: "3.4",
+		},
+		{
+			name:     "pins versions of unmet dependency",
+			versions: map[string][]string{"chrony": {"3.5"}, "libseccomp2": {"2.6", "2.5", "2.4"}},
+			installs: map[string]InstallResult{
+				"chrony=3.5":                 unmet(Relation{Name: "libseccomp2", Operator: ">=", Version: "2.5"}),
+				"chrony=3.5 libseccomp2=2.5": ok,
+			},
+			wantChecked: []string{"chrony=3.5", "chrony=3.5 libseccomp2=2.6", "chrony=3.5 libseccomp2=2.5"},
+			wantVersion: "3.5",
+		},
+		{
+			name:     "tries next alternative without candidates",
+			versions: map[string][]string{"chrony": {"3.5"}, "libfoo": {}, "libbar": {"1.0"}},
+			installs: map[string]InstallResult{
+				"chrony=3.5":            unmet(Relation{Name: "libfoo"}, Relation{Name: "libbar"}),
+				"chrony=3.5 libbar=1.0": ok,
+			},
+			wantChecked: []string{"chrony=3.5", "chrony=3.5 libbar=1.0"},
+			wantVersion: "3.5",
+		},
+		{
+			name:     "stops at the maximum depth",
+			versions: map[string][]string{"chrony": {"3.5"}, "liba": {"1"}, "libb": {"1"}, "libc": {"1"}},
+			installs: map[string]InstallResult{
+				"chrony=3.5":               unmet(Relation{Name: "liba"}),
+				"chrony=3.5 liba=1":        unmet(Relation{Name: "libb"}),
+				"chrony=3.5 liba=1 libb=1": unmet(Relation{Name: "libc"}),
+			},
+			wantChecked: []string{"chrony=3.5", "chrony=3.5 liba=1", "chrony=3.5 liba=1 libb=1"},
+			wantLog:     "I couldn't find an installable version of the package.",
+		},
+		{
+			name:     "tries a limited number of dependency candidates",
+			versions: map[string][]string{"chrony": {"3.5"}, "liba": {"7", "6", "5", "4", "3", "2", "1"}},
+			installs: map[string]InstallResult{"chrony=3.5": unmet(Relation{Name: "liba"})},
+			wantChecked: []string{"chrony=3.5", "chrony=3.5 liba=7", "chrony=3.5 liba=6", "chrony=3.5 liba=5",
+				"chrony=3.5 liba=4", "chrony=3.5 liba=3"},
+			wantLog: "I couldn't find an installable version of the package.",
+		},
+		{
+			name:     "stops after the maximum number of tried sets",
+			versions: map[string][]string{"chrony": manyVersions},
+			installs: map[string]InstallResult{"chrony=" + manyVersions[len(manyVersions)-1]: ok},
+			wantLog:  fmt.Sprintf("I tried %d sets of packages and I'm going to stop searching.", maxTriedSets),
+		},
+		{
+			name:     "skips versions that remove node packages",
+			versions: map[string][]string{"chrony": {"3.5", "3.4"}},
+			installs: map[string]InstallResult{
+				"chrony=3.5": {Result: ResultOk, Transaction: Transaction{Removals: []Change{{Name: "ntp"}}}},
+				"chrony=3.4": ok,
+			},
+			wantChecked: []string{"chrony=3.5", "chrony=3.4"},
+			wantVersion: "3.4",
+			wantLog: "Installation of chrony=3.5 would remove the following node packages, which the policy " +
+				"doesn't allow: ntp.",
+		},
+		{
+			name:        "tries only versions the policy allows",
+			versions:    map[string][]string{"chrony": {"4.0", "3.5"}},
+			installs:    map[string]InstallResult{"chrony=4.0": ok, "chrony=3.5": ok},
+			policy:      Policy{MaxVersionBump: "minor"},
+			wantChecked: []string{"chrony=3.5"},
+			wantVersion: "3.5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The installable sets can be downloaded.
+			downloads := make(map[string][]string)
+			for set, r := range tt.installs {
+				if r.Result == ResultOk {
+					version := strings.TrimPrefix(strings.Fields(set)[0], "chrony=")
+					downloads[set] = []string{fmt.Sprintf("chrony_%s_amd64.deb", version)}
+				}
+			}
+			m := &fakeManager{versions: tt.versions, installs: tt.installs, downloads: downloads}
+			b, err := newFakeBundle(m, "chrony/chrony_3.2_amd64.deb")
+			if err != nil {
+				t.Fatalf("newFakeBundle() error = %v", err)
+			}
+			b.Policy = tt.policy
+			res, err := SearchVersions(context.Background(), b.Packages[0], b, &FixResult{Package: b.Packages[0]})
+			if err != nil {
+				t.Fatalf("SearchVersions() error = %v", err)
+			}
+			if tt.wantChecked != nil && !reflect.DeepEqual(m.checked, tt.wantChecked) {
+				t.Errorf("SearchVersions() checked %v, want %v", m.checked, tt.wantChecked)
+			}
+			if tt.wantVersion == "" && (res.Success || len(m.checked) > maxTriedSets) {
+				t.Errorf("SearchVersions() success = %v after %d sets, want a failure after at most %d sets",
+					res.Success, len(m.checked), maxTriedSets)
+			}
+			if tt.wantVersion != "" && (!res.Success || res.Package.Version != tt.wantVersion) {
+				t.Errorf("SearchVersions() success = %v, version = %s, want version %s", res.Success,
+					res.Package.Version, tt.wantVersion)
+			}
+			if log := strings.Join(res.Log, "\n"); !strings.Contains(log, tt.wantLog) {
+				t.Errorf("SearchVersions() log = %q, want it to contain %q", log, tt.wantLog)
+			}
+		})
+	}
+}
@@ -1,22 +1,58 @@
 package bundle
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"path"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/disiqueira/gotree"
 )
 
-func CheckAndFixBundle(b *Bundle) {
+const (
+	maxLockRetries = 5
+	lockRetryDelay = 10 * time.Second
+)
+
+// BundleFixResult is the result of checking and fixing the whole bundle.
+type BundleFixResult struct {
+	// Bundle is the fixed bundle.
+	Bundle *Bundle
+	// Results are the outcomes of fixing every main package of the bundle in the bundle order.
+	Results           []*FixResult
+	Unresolved        []string
+	Incompatibilities []Incompatibility
+	Vulnerabilities   []Vulnerability
+	// IncompatibilitiesErr and VulnerabilitiesErr are the errors of the checks of the fixed bundle. They don't
+	// stop fixing.
+	IncompatibilitiesErr error
+	VulnerabilitiesErr   error
+	// Pruned are the dependencies dropped from the fixed bundle because the base image has them. It's nil when
+	// the bundle has no installed packages to prune against.
+	Pruned *PruneResult
+	// InitialTree and FixedTree are the printed package trees of the bundle before and after fixing and pruning.
+	InitialTree string
+	FixedTree   string
+}
+
+// CheckAndFixBundle fixes the bundle in place. Use Fix to keep the bundle intact. It stops with the context error
+// when the context is done.
+func CheckAndFixBundle(ctx context.Context, b *Bundle) (*BundleFixResult, error) {
 	initialBundleTree := printBundleTree(b, "Initial package bundle")
 	newPackages := make([]*Package, len(b.Packages))
 	var unresolvedPackages []string
+	results := make([]*FixResult, len(b.Packages))
+	progress := ProgressFrom(ctx)
 	for i, p := range b.Packages {
-		res := &FixResult{Log: make([]string, 0)}
-		res.AttemptsLeft = 3
-		res = CheckAndFixPackage(p, b, res)
-		fmt.Println(strings.Join(res.Log, "\n"))
-		fmt.Println()
+		progress.StartPackage(i+1, len(b.Packages), p.Name)
+		res, err := fixPackageWithCheckpoint(ctx, i, p, b)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = res
 		newPackages[i] = res.Package
 		if !res.Success {
 			newPackages[i] = p
@@ -24,28 +60,125 @@ func CheckAndFixBundle(b *Bundle) {
 		}
 	}
 	b.Packages = newPackages
-	resultedBundleTree := printBundleTree(b, "Fixed package bundle")
-	if len(unresolvedPackages) > 0 {
-		fmt.Printf("The following packages were not fixed:\n%s", strings.Join(unresolvedPackages, "\n"))
+	bundleRes := &BundleFixResult{
+		Bundle:      b,
+		Results:     results,
+		Unresolved:  unresolvedPackages,
+		InitialTree: initialBundleTree,
+	}
+	// The fixed bundle is pruned before the checks, so that the tree and the checks match the written bundle.
+	if b.Installed != nil {
+		pruned, err := PruneDependencies(b, b.Installed)
+		if err != nil {
+			return nil, fmt.Errorf("cannot prune the dependencies of the fixed bundle. Error: %w", err)
+		}
+		bundleRes.Pruned = pruned
+	}
+	bundleRes.FixedTree = printBundleTree(b, "Fixed package bundle")
+	bundleRes.Incompatibilities, bundleRes.IncompatibilitiesErr = FindIncompatibilities(ctx, b)
+	if len(b.Advisories) > 0 {
+		bundleRes.Vulnerabilities, bundleRes.VulnerabilitiesErr = FindVulnerabilities(ctx, b, b.Advisories)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("checks of the fixed bundle stopped. Error: %w", err)
+	}
+	return bundleRes, nil
+}
+
+// fixPackageWithCheckpoint restores the outcome of the main package at the index from the bundle checkpoint or
+// fixes the package and saves the outcome to the checkpoint.
+func fixPackageWithCheckpoint(ctx context.Context, index int, p *Package, b *Bundle) (*FixResult, error) {
+	if b.Checkpoint != nil {
+		res, err := b.Checkpoint.Load(index, p)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			res.AddLog("I restored the outcome of fixing the package from the checkpoint of the previous run.")
+			return res, nil
+		}
+	}
+	res := &FixResult{Log: make([]string, 0), Original: p}
+	res = CheckAndFixPackage(ctx, p, b, res)
+	// The outcome of a stopped run is incomplete, so it's not saved.
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("fixing of the bundle stopped at package %s. Error: %w", p.Name, err)
+	}
+	// Failures caused by errors or a held lock may go away on the next run, so they are not saved either.
+	if b.Checkpoint != nil && !res.retryable {
+		if err := b.Checkpoint.Save(index, res); err != nil {
+			return nil, fmt.Errorf("cannot save the checkpoint of package %s. Error: %w", p.Name, err)
+		}
+	}
+	return res, nil
+}
+
+// Print prints the log of every package, the unresolved packages, the policy violations, the bundle trees and
+// the results of the checks of the fixed bundle.
+func (r *BundleFixResult) Print(w io.Writer) {
+	for _, res := range r.Results {
+		_, _ = fmt.Fprintf(w, "%s\n\n", strings.Join(res.Log, "\n"))
+	}
+	if len(r.Unresolved) > 0 {
+		_, _ = fmt.Fprintf(w, "The following packages were not fixed:\n%s", strings.Join(r.Unresolved, "\n"))
+	}
+	var violations []string
+	for _, res := range r.Results {
+		violations = append(violations, res.Violations...)
+	}
+	if len(violations) > 0 {
+		_, _ = fmt.Fprintf(w, "\nThe fixes were stopped by the following policy violations:\n%s\n",
+			strings.Join(violations, "\n"))
+	}
+	if r.Pruned != nil {
+		_, _ = fmt.Fprintf(w, "\n%s\n", r.Pruned)
+	}
+	_, _ = fmt.Fprintf(w, "Initial bundle package tree:\n%s\n", r.InitialTree)
+	_, _ = fmt.Fprintf(w, "Resulted bundle package tree:\n%s", r.FixedTree)
+	if r.IncompatibilitiesErr != nil {
+		_, _ = fmt.Fprintf(w, "\nCouldn't check if the bundle packages are compatible with each other due to the "+
+			"following error: %v\n", r.IncompatibilitiesErr)
+	} else if len(r.Incompatibilities) > 0 {
+		_, _ = fmt.Fprintf(w, "\nThe following bundle packages cannot be installed together:\n%s\n",
+			printIncompatibilities(r.Incompatibilities))
+	}
+	if len(r.Bundle.Advisories) == 0 {
+		return
+	}
+	if r.VulnerabilitiesErr != nil {
+		_, _ = fmt.Fprintf(w, "\nCouldn't match the bundle packages against the security advisories due to the "+
+			"following error: %v\n", r.VulnerabilitiesErr)
+	} else if len(r.Vulnerabilities) > 0 {
+		_, _ = fmt.Fprintf(w, "\nThe following bundle packages are affected by known vulnerabilities:\n%s\n",
+			printVulnerabilities(r.Vulnerabilities))
+	} else {
+		_, _ = fmt.Fprintln(w, "\nNo bundle packages are affected by known vulnerabilities.")
 	}
-	fmt.Printf("Initial bundle package tree:\n%s", initialBundleTree)
-	fmt.Println()
-	fmt.Printf("Resulted bundle package tree:\n%s", resultedBundleTree)
 }
 
-func CheckAndFixPackage(p *Package, b *Bundle, res *FixResult) *FixResult {
+func CheckAndFixPackage(ctx context.Context, p *Package, b *Bundle, res *FixResult) *FixResult {
 	res.AddLog(fmt.Sprintf("I'm going to check if it's possible to install package \"%s\". "+
 		"I'll try to update the package and its dependencies if it's not.", p.Name))
-	res, err := SimulateInstallation(p, b, res)
+	res.visitState(p)
+	res, err := SimulateInstallation(ctx, p, b, res)
 	if err != nil {
 		res.AddLog(fmt.Sprintf("The following error occurred during fixing the package bundle: %v\n", err))
 		res.AddLog("Unfortunately, I couldn't make this package installable on this machine.")
+		res.retryable = true
 		return res
 	}
-	if !res.Success && res.Repeat && res.AttemptsLeft > 0 {
-		res.AttemptsLeft--
-		res.AddLog(fmt.Sprintf("Going again. Attempts left: %d.", res.AttemptsLeft))
-		return CheckAndFixPackage(p, b, res)
+	if !res.Success && res.Repeat {
+		res.Repeat = false
+		// Dependencies only grow, so the same state means that no new dependencies were added.
+		p = res.Package
+		if !res.visitState(p) {
+			res.AddLog("The package dependencies didn't change since one of the previous checks, so the " +
+				"dependencies form a cycle I cannot resolve. The dependency chain that could not be closed: " +
+				res.printChain(p))
+		} else {
+			res.AddLog("New dependencies were added, so I'm going again.")
+			return CheckAndFixPackage(ctx, p, b, res)
+		}
 	}
 	if res.Success {
 		res.AddLog("SUCCESS")
@@ -55,18 +188,45 @@ func CheckAndFixPackage(p *Package, b *Bundle, res *FixResult) *FixResult {
 	return res
 }
 
-func SimulateInstallation(p *Package, b *Bundle, res *FixResult) (*FixResult, error) {
+func SimulateInstallation(ctx context.Context, p *Package, b *Bundle, res *FixResult) (*FixResult, error) {
 	m := b.Manager
+	if p.Corrupt {
+		res.AddLog("Cannot install the package in its current state. Reason: the package file failed the " +
+			"integrity verification and was quarantined.")
+		return WhenArchiveCorrupt(ctx, p, b, res)
+	}
 	res.AddLog("I'm going to simulate installation of the package.")
-	r, err := m.CheckInstall(p)
+	r, err := m.CheckInstall(ctx, p)
 	if err != nil {
 		res.AddLog("Couldn't simulate installation due to the following error: " + err.Error())
 		return res, err
 	}
+	res.Simulations = append(res.Simulations, r)
 	switch r.Result {
 	case ResultOk:
+		if !r.Transaction.Empty() {
+			res.AddLog(fmt.Sprintf("The installation would change the node as follows (%s):\n%s",
+				r.Transaction.Summary(), r.Transaction))
+		}
+		if removals := b.Policy.DisallowedRemovals(r.Transaction); len(removals) > 0 {
+			res.AddLog("Cannot install the package in its current state. Reason: the installation would remove " +
+				"the following node packages, which the policy doesn't allow:\n" +
+				strings.Join(explainRemovals(ctx, p, b, removals), "\n"))
+			return WhenOtherProblemsOccurred(ctx, p, b, res)
+		}
+		if b.PreferFixedVersions && !p.VersionEssential && !b.Policy.Denies(p.Name) {
+			replaced, err := preferFixedVersion(ctx, p, b, res)
+			if err != nil || replaced {
+				return res, err
+			}
+		}
+		if added := addedDependencies(p, r.Transaction); len(added) > 0 && !b.Policy.AllowsNewDependencies(p.Name) {
+			res.addViolation(fmt.Sprintf("%s %s would gain new dependencies %s, but the policy doesn't allow %s "+
+				"to gain new dependencies", p.Name, p.Version, strings.Join(added, ", "), p.Name))
+			return res, nil
+		}
 		res.AddLog("Simulated installation was successful. I'm going to download dependencies.")
-		err = m.UpdateDependencies(p)
+		err = m.UpdateDependencies(ctx, p)
 		if err != nil {
 			res.AddLog("Couldn't update package dependencies due to the following error: " + err.Error())
 			return res, err
@@ -78,55 +238,126 @@ func SimulateInstallation(p *Package, b *Bundle, res *FixResult) (*FixResult, er
 	case ResultUnmetDependencies:
 		res.AddLog("Cannot install the package in its current state. Reason: " +
 			"the following dependencies were not met:\n" + printDependencyList(r))
-		return WhenDependencyNotMet(p, b, r, res)
+		return WhenDependencyNotMet(ctx, p, b, r, res)
 	case ResultNewerAlreadyInstalled:
 		res.AddLog("Cannot install the package in its current state. Reason: " +
 			"a newer version of the package is already installed.")
-		return WhenOtherProblemsOccurred(p, b, res)
+		return WhenOtherProblemsOccurred(ctx, p, b, res)
+	case ResultEssentialRemoval:
+		res.AddLog("Cannot install the package in its current state. Reason: the installation would remove " +
+			"the following essential packages: " + strings.Join(r.Details, ", ") + ".")
+		return WhenOtherProblemsOccurred(ctx, p, b, res)
+	case ResultConflicts:
+		res.AddLog("Cannot install the package in its current state. Reason: the package conflicts with other " +
+			"packages:\n" + strings.Join(r.Details, "\n"))
+		return WhenOtherProblemsOccurred(ctx, p, b, res)
+	case ResultHeldBrokenPackages:
+		res.AddLog("Cannot install the package in its current state. Reason: held or broken packages prevent " +
+			"the installation: " + strings.Join(r.Details, ", ") + ". If the packages are held on this machine, " +
+			"release them with \"apt-mark unhold\" and run me again.")
+		return WhenOtherProblemsOccurred(ctx, p, b, res)
+	case ResultLockContention:
+		return WhenLocked(ctx, p, b, r, res)
+	case ResultCorruptArchive:
+		res.AddLog("Cannot install the package in its current state. Reason: the package file is corrupt:\n" +
+			strings.Join(r.Details, "\n"))
+		return WhenArchiveCorrupt(ctx, p, b, res)
+	case ResultPackagesRemoved:
+		res.AddLog("Cannot install the package in its current state. Reason: the installation would remove " +
+			"the following packages: " + strings.Join(r.Details, ", ") + ".")
+		return WhenOtherProblemsOccurred(ctx, p, b, res)
 	default:
 		res.AddLog("Cannot install the package in its current state.")
-		return WhenOtherProblemsOccurred(p, b, res)
+		return WhenOtherProblemsOccurred(ctx, p, b, res)
 	}
 }
 
-func WhenOtherProblemsOccurred(p *Package, b *Bundle, res *FixResult) (*FixResult, error) {
-	if !p.VersionEssential {
+func WhenOtherProblemsOccurred(ctx context.Context, p *Package, b *Bundle, res *FixResult) (*FixResult, error) {
+	if replaceable(p, b, res) {
 		res.AddLog("The version of the package is not essential, " +
-			"so I'm going to replace it with the latest version.")
-		return ReplaceWithLatestVersion(p, b, res)
+			"so I'm going to replace it with the newest installable version.")
+		return SearchVersions(ctx, p, b, res)
+	}
+	if !p.VersionEssential {
+		res.AddLog("The policy doesn't allow to replace the package, so I'm not going to proceed.")
+		return res, nil
 	}
 	res.AddLog("It is important to install this exact version of the package, but it's not possible. " +
 		"Please try to install download the package and its dependencies manually.")
 	return res, nil
 }
 
-func WhenDependencyNotMet(p *Package, b *Bundle, r InstallResult, res *FixResult) (*FixResult, error) {
-	if !p.VersionEssential {
-		res.AddLog("The version of the package is not essential, " +
-			"so I'm going to replace it with the latest version.")
-		return ReplaceWithLatestVersion(p, b, res)
+// WhenLocked waits for another process to release the package manager lock and simulates installation again.
+func WhenLocked(ctx context.Context, p *Package, b *Bundle, r InstallResult, res *FixResult) (*FixResult, error) {
+	if res.lockRetries >= maxLockRetries {
+		res.AddLog(fmt.Sprintf("Another process still holds the package manager lock %s after %d retries. "+
+			"Please, stop it and run me again.", strings.Join(r.Details, ", "), res.lockRetries))
+		res.retryable = true
+		return res, nil
 	}
-	res.AddLog("It is important to install this exact version of the package. I'm going to search for the " +
-		"dependencies in the package bundle.")
-	allPackages := make(map[string]*Package)
-	for _, p := range b.Packages {
-		allPackages[p.Name] = p
-		for _, d := range p.Dependencies {
-			allPackages[d.Name] = d
-		}
+	res.lockRetries++
+	res.AddLog(fmt.Sprintf("Another process holds the package manager lock %s. I'm going to wait for %v "+
+		"and try again.", strings.Join(r.Details, ", "), lockRetryDelay))
+	select {
+	case <-ctx.Done():
+		return res, ctx.Err()
+	case <-time.After(lockRetryDelay):
+	}
+	return SimulateInstallation(ctx, p, b, res)
+}
+
+// WhenArchiveCorrupt downloads the same version of the package again and simulates installation of it.
+func WhenArchiveCorrupt(ctx context.Context, p *Package, b *Bundle, res *FixResult) (*FixResult, error) {
+	if res.redownloaded {
+		res.AddLog("The downloaded package file is corrupt too. Please, check the package sources.")
+		return res, nil
 	}
+	res.redownloaded = true
+	res.AddLog(fmt.Sprintf("I'm going to download version %s of the package again.", p.Version))
+	newPackage, err := b.Manager.DownloadSet(ctx, path.Base(path.Dir(p.Path)), []NameVersion{p.NameVersion})
+	if err != nil {
+		res.AddLog("Couldn't download the package due to the following error: " + err.Error())
+		return res, err
+	}
+	newPackage.Origin = OriginReplaced
+	return SimulateInstallation(ctx, newPackage, b, res)
+}
+
+func WhenDependencyNotMet(ctx context.Context, p *Package, b *Bundle, r InstallResult,
+	res *FixResult) (*FixResult, error) {
+	if replaceable(p, b, res) {
+		res.AddLog("The version of the package is not essential, " +
+			"so I'm going to replace it with the newest installable version.")
+		return SearchVersions(ctx, p, b, res)
+	}
+	res.AddLog("It is important to install this exact version of the package or the policy doesn't allow to " +
+		"replace it. I'm going to search for the dependencies in the package bundle.")
+	res.Chain = append(res.Chain, strings.Replace(printDependencyList(r), "\n", ", ", -1))
 	var somePackagesNotFound bool
 	for _, ud := range r.UnmetDependencies {
-		found, ok := allPackages[ud.Name]
-		if !ok {
-			res.AddLog(fmt.Sprintf("Couldn't find required dependency package %s in the bundle.", ud.Name))
+		found, explanation, err := findDependency(ctx, p, b, ud)
+		if err != nil {
+			res.AddLog("Couldn't search for the dependency in the bundle due to the following error: " + err.Error())
+			return res, err
+		}
+		res.AddLog(explanation)
+		if found == nil {
 			somePackagesNotFound = true
+			continue
+		}
+		if hasDependency(p, found) {
+			continue
+		}
+		if !b.Policy.AllowsNewDependencies(p.Name) {
+			res.addViolation(fmt.Sprintf("%s %s would gain new dependency %s, but the policy doesn't allow %s "+
+				"to gain new dependencies", p.Name, p.Version, found.Name, p.Name))
+			return res, nil
 		}
 		p.Dependencies = append(p.Dependencies, found)
-		res.AddLog(fmt.Sprintf("Package %s found and added to the dependencies.", ud.Name))
 	}
 	if somePackagesNotFound {
-		res.AddLog("I couldn't find some dependencies in the bundle. Please try to find them manually.")
+		res.AddLog("I couldn't find some dependencies in the bundle. Please try to find them manually. " +
+			"The dependency chain that could not be closed: " + res.printChain(p))
 		return res, nil
 	}
 	res.AddLog("All the required packages were found and added to the package dependencies. I'm going to check " +
@@ -136,55 +367,167 @@ func WhenDependencyNotMet(p *Package, b *Bundle, r InstallResult, res *FixResult
 	return res, nil
 }
 
-func ReplaceWithLatestVersion(p *Package, b *Bundle, res *FixResult) (*FixResult, error) {
-	res.AddLog("Check if it's possible to install the latest version of the package.")
-	r, err := b.Manager.CheckInstallLatestVersion(p.Name)
+// replaceable returns true if the package version is not essential and the policy allows to replace it.
+// It records the violation when the policy denies the replacement.
+func replaceable(p *Package, b *Bundle, res *FixResult) bool {
+	if p.VersionEssential {
+		return false
+	}
+	if b.Policy.Denies(p.Name) {
+		res.addViolation(fmt.Sprintf("the policy denies replacing %s", p.Name))
+		return false
+	}
+	return true
+}
+
+// ReplaceWithVersion replaces the package with the given version of it. Empty version means the latest one.
+func ReplaceWithVersion(ctx context.Context, p *Package, b *Bundle, version string,
+	res *FixResult) (*FixResult, error) {
+	versionName := "the latest version"
+	if version != "" {
+		versionName = fmt.Sprintf("version %s", version)
+	}
+	res.AddLog(fmt.Sprintf("Check if it's possible to install %s of the package.", versionName))
+	r, err := b.Manager.CheckInstallSet(ctx, []NameVersion{{Name: p.Name, Version: version}})
 	if err != nil {
-		res.AddLog("Couldn't check if it's possible to install the latest version due to the following error: " +
-			err.Error())
+		res.AddLog(fmt.Sprintf("Couldn't check if it's possible to install %s due to the following error: %v",
+			versionName, err))
 		return res, err
 	}
-	if r != ResultOk {
-		res.AddLog("It's not possible to install the latest version of the package. Please, contact support and " +
-			"provide them this output.")
+	if r.Result != ResultOk {
+		res.AddLog(fmt.Sprintf("It's not possible to install %s of the package. Please, contact support and "+
+			"provide them this output.", versionName))
+		return res, nil
+	}
+	if removals := b.Policy.DisallowedRemovals(r.Transaction); len(removals) > 0 {
+		names := make([]string, len(removals))
+		for i, c := range removals {
+			names[i] = c.Name
+		}
+		res.AddLog(fmt.Sprintf("Installation of %s of the package would remove the following node packages, "+
+			"which the policy doesn't allow: %s.", versionName, strings.Join(names, ", ")))
+		return res, nil
+	}
+	res.AddLog(fmt.Sprintf("It is possible to install %s of the package. I'm going to download the package "+
+		"and its dependencies.", versionName))
+	var newPackage *Package
+	if version == "" {
+		newPackage, err = b.Manager.DownloadLatestVersion(ctx, p.Name)
+	} else {
+		newPackage, err = b.Manager.DownloadVersion(ctx, p.Name, version)
 	}
-	res.AddLog("It is possible to install the latest version of the package. I'm going to download the package " +
-		"and its dependencies.")
-	newPackage, err := b.Manager.DownloadLatestVersion(p.Name)
 	if err != nil {
-		res.AddLog("Couldn't download the latest version of the package or its dependencies " +
-			"due to the following error: " + err.Error())
+		res.AddLog(fmt.Sprintf("Couldn't download %s of the package or its dependencies "+
+			"due to the following error: %v", versionName, err))
 		return res, err
 	}
-	res.Success = true
-	res.Package = newPackage
-	return res, nil
+	newPackage.Origin = OriginReplaced
+	return approveReplacement(ctx, p, newPackage, b, res)
 }
 
 type FixResult struct {
-	Log          []string
-	Success      bool
-	Package      *Package
-	Repeat       bool
-	AttemptsLeft int
+	Log     []string
+	Success bool
+	// Original is the bundle package before fixing.
+	Original *Package
+	Package  *Package
+	Repeat   bool
+	// Chain lists the unmet dependencies found on every resolution step.
+	Chain []string
+	// Simulations are the results of every simulated installation of the package in order.
+	Simulations []InstallResult
+	// Decisions are the decisions made about the proposed replacements of the package.
+	Decisions []ReplacementDecision
+	// Violations are the policy guardrails the proposed fixes of the package would break.
+	Violations   []string
+	states       map[string]bool
+	lockRetries  int
+	redownloaded bool
+	// retryable means that the package failed for a reason that may go away on the next run, for example
+	// an error or a held lock.
+	retryable bool
 }
 
 func (r *FixResult) AddLog(l string) {
 	r.Log = append(r.Log, l)
 }
 
+func (r *FixResult) addViolation(v string) {
+	r.Violations = append(r.Violations, v)
+	r.AddLog("Policy violation: " + v + ".")
+}
+
+// addedDependencies returns the names of the packages the transaction installs besides the package and
+// its current dependencies.
+func addedDependencies(p *Package, t Transaction) []string {
+	names := map[string]bool{p.Name: true}
+	for _, d := range p.Dependencies {
+		names[d.Name] = true
+	}
+	added := make([]string, 0)
+	for _, c := range t.Installs {
+		if !names[c.Name] {
+			names[c.Name] = true
+			added = append(added, c.Name)
+		}
+	}
+	return added
+}
+
+// visitState remembers the current dependencies of the package. It returns false if they were already seen.
+func (r *FixResult) visitState(p *Package) bool {
+	paths := make([]string, len(p.Dependencies))
+	for i, d := range p.Dependencies {
+		paths[i] = d.Path
+	}
+	sort.Strings(paths)
+	state := strings.Join(paths, "\n")
+	if r.states == nil {
+		r.states = make(map[string]bool)
+	}
+	if r.states[state] {
+		return false
+	}
+	r.states[state] = true
+	return true
+}
+
+func (r *FixResult) printChain(p *Package) string {
+	return strings.Join(append([]string{p.Name}, r.Chain...), " -> ")
+}
+
 func printDependencyList(r InstallResult) string {
 	deps := make([]string, len(r.UnmetDependencies))
 	for i, d := range r.UnmetDependencies {
-		if d.Version == "" {
-			deps[i] = fmt.Sprintf("%s", d.Name)
-			continue
-		}
-		deps[i] = fmt.Sprintf("%s (>= %s)", d.Name, d.Version)
+		deps[i] = printAlternatives(d)
 	}
 	return strings.Join(deps, "\n")
 }
 
+func printAlternatives(alternatives []Relation) string {
+	aa := make([]string, len(alternatives))
+	for i, a := range alternatives {
+		aa[i] = a.String()
+	}
+	return strings.Join(aa, " | ")
+}
+
+func printIncompatibilities(incompatibilities []Incompatibility) string {
+	lines := make([]string, len(incompatibilities))
+	for i, inc := range incompatibilities {
+		lines[i] = inc.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func printVulnerabilities(vulnerabilities []Vulnerability) string {
+	lines := make([]string, len(vulnerabilities))
+	for i, v := range vulnerabilities {
+		lines[i] = v.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
 func printBundleTree(b *Bundle, bundleName string) string {
 	bundleNode := gotree.New(bundleName)
 	for _, p := range b.Packages {
@@ -0,0 +1,193 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReplaceWithVersion(t *testing.T) {
+	removal := InstallResult{Result: ResultOk, Transaction: Transaction{
+		Installs: []Change{{Name: "chrony", NewVersion: "3.5"}},
+		Removals: []Change{{Name: "ntp", OldVersion: "4.2"}},
+	}}
+	tests := []struct {
+		name    string
+		version string
+		policy  Policy
+		// wantErr is the download error, which means the replacement was checked and accepted.
+		wantErr error
+		wantLog string
+	}{
+		{
+			name:    "rejects pinned version that removes node packages",
+			version: "3.5",
+			wantLog: "would remove the following node packages, which the policy doesn't allow: ntp.",
+		},
+		{
+			name:    "rejects latest version that removes node packages",
+			wantLog: "would remove the following node packages, which the policy doesn't allow: ntp.",
+		},
+		{
+			name:    "downloads version when the policy allows removals",
+			version: "3.5",
+			policy:  Policy{AllowedRemovals: []string{"ntp"}},
+			wantErr: errNotSupported,
+			wantLog: "It is possible to install version 3.5 of the package.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &fakeManager{installs: map[string]InstallResult{"chrony": removal, "chrony=3.5": removal}}
+			b, err := newFakeBundle(m, "chrony/chrony_3.2_amd64.deb")
+			if err != nil {
+				t.Fatalf("newFakeBundle() error = %v", err)
+			}
+			b.Policy = tt.policy
+			res, err := ReplaceWithVersion(context.Background(), b.Packages[0], b, tt.version, &FixResult{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReplaceWithVersion() error = %v, want %v", err, tt.wantErr)
+			}
+			if res.Success {
+				t.Errorf("ReplaceWithVersion() success = true, want false")
+			}
+			if log := strings.Join(res.Log, "\n"); !strings.Contains(log, tt.wantLog) {
+				t.Errorf("ReplaceWithVersion() log = %q, want it to contain %q", log, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestCheckAndFixPackage_Cycle(t *testing.T) {
+	tests := []struct {
+		name            string
+		paths           []string
+		unmet           string
+		wantSimulations int
+		wantChain       string
+	}{
+		{
+			name:            "stops when dependencies don't change",
+			paths:           []string{"chrony=3.2/chrony_3.2_amd64.deb", "chrony=3.2/libseccomp2_2.5_amd64.deb"},
+			unmet:           "libseccomp2",
+			wantSimulations: 1,
+			wantChain:       "chrony -> libseccomp2",
+		},
+		{
+			name:            "adds dependencies until they stop changing",
+			paths:           []string{"chrony=3.2/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb", "ntp/libcap_2.2_amd64.deb"},
+			unmet:           "libcap",
+			wantSimulations: 2,
+			wantChain:       "chrony -> libcap -> libcap",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The package manager doesn't see the added dependency, so the dependency stays unmet.
+			m := &fakeManager{installs: map[string]InstallResult{
+				"chrony": {Result: ResultUnmetDependencies, UnmetDependencies: [][]Relation{{{Name: tt.unmet}}}},
+			}}
+			b, err := newFakeBundle(m, tt.paths...)
+			if err != nil {
+				t.Fatalf("newFakeBundle() error = %v", err)
+			}
+			p := b.Packages[0]
+			res := CheckAndFixPackage(context.Background(), p, b, &FixResult{Original: p})
+			if res.Success || len(res.Simulations) != tt.wantSimulations {
+				t.Errorf("CheckAndFixPackage() success = %v, simulations = %d, want a failure after %d",
+					res.Success, len(res.Simulations), tt.wantSimulations)
+			}
+			wantLog := "the dependencies form a cycle I cannot resolve. The dependency chain that could not be " +
+				"closed: " + tt.wantChain
+			if log := strings.Join(res.Log, "\n"); !strings.Contains(log, wantLog) {
+				t.Errorf("CheckAndFixPackage() log = %q, want it to contain %q", log, wantLog)
+			}
+		})
+	}
+}
+
+func TestSimulateInstallation_NoNewDependencies(t *testing.T) {
+	gains := InstallResult{Result: ResultOk, Transaction: Transaction{
+		Installs: []Change{{Name: "chrony", NewVersion: "3.2"}, {Name: "libseccomp2", NewVersion: "2.5"}},
+	}}
+	tests := []struct {
+		name     string
+		paths    []string
+		installs map[string]InstallResult
+		policy   Policy
+		// wantErr is the dependency update error, which means the new dependencies were accepted.
+		wantErr       error
+		wantViolation string
+	}{
+		{
+			name:          "rejects dependencies of an installable package",
+			paths:         []string{"chrony/chrony_3.2_amd64.deb"},
+			installs:      map[string]InstallResult{"chrony": gains},
+			policy:        Policy{NoNewDependencies: []string{"chrony"}},
+			wantViolation: "chrony 3.2 would gain new dependencies libseccomp2",
+		},
+		{
+			name:     "downloads dependencies the policy allows",
+			paths:    []string{"chrony/chrony_3.2_amd64.deb"},
+			installs: map[string]InstallResult{"chrony": gains},
+			wantErr:  errNotSupported,
+		},
+		{
+			name:  "rejects dependencies found in the bundle",
+			paths: []string{"chrony=3.2/chrony_3.2_amd64.deb", "ntp/ntp_4.2_amd64.deb", "ntp/libcap_2.2_amd64.deb"},
+			installs: map[string]InstallResult{
+				"chrony": {Result: ResultUnmetDependencies, UnmetDependencies: [][]Relation{{{Name: "libcap"}}}},
+			},
+			policy:        Policy{NoNewDependencies: []string{"chrony"}},
+			wantViolation: "chrony 3.2 would gain new dependency libcap",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := newFakeBundle(&fakeManager{installs: tt.installs}, tt.paths...)
+			if err != nil {
+				t.Fatalf("newFakeBundle() error = %v", err)
+			}
+			b.Policy = tt.policy
+			p := b.Packages[0]
+			res, err := SimulateInstallation(context.Background(), p, b, &FixResult{Original: p})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SimulateInstallation() error = %v, want %v", err, tt.wantErr)
+			}
+			if res.Success || res.Repeat {
+				t.Errorf("SimulateInstallation() success = %v, repeat = %v, want neither", res.Success, res.Repeat)
+			}
+			if len(p.Dependencies) != 0 {
+				t.Errorf("SimulateInstallation() dependencies = %v, want none", p.Dependencies)
+			}
+			violations := strings.Join(res.Violations, "\n")
+			if (tt.wantViolation == "") != (violations == "") || !strings.Contains(violations, tt.wantViolation) {
+				t.Errorf("SimulateInstallation() violations = %q, want %q", violations, tt.wantViolation)
+			}
+		})
+	}
+}
+
+func TestSimulateInstallation_PreferFixedVersionViolations(t *testing.T) {
+	m := &fakeManager{
+		installs: map[string]InstallResult{"chrony": {Result: ResultOk}},
+		versions: map[string][]string{"chrony": {"4.0"}},
+	}
+	b, err := newFakeBundle(m, "chrony/chrony_3.2_amd64.deb")
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	b.Advisories = []Advisory{{ID: "USN-4001-1", Package: "chrony", FixedVersion: "3.5"}}
+	b.PreferFixedVersions = true
+	b.Policy = Policy{MaxVersionBump: "minor"}
+	p := b.Packages[0]
+	// The package stays as it is, so the dependency update is reached.
+	res, err := SimulateInstallation(context.Background(), p, b, &FixResult{Original: p})
+	if !errors.Is(err, errNotSupported) {
+		t.Fatalf("SimulateInstallation() error = %v, want %v", err, errNotSupported)
+	}
+	if len(res.Violations) != 0 {
+		t.Errorf("SimulateInstallation() violations = %v, want none for the rejected fixed versions", res.Violations)
+	}
+}
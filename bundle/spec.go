@@ -0,0 +1,102 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Spec declaratively describes the bundle to build.
+type Spec struct {
+	Packages []PackageSpec `json:"packages"`
+}
+
+// PackageSpec describes a package directory of the bundle.
+type PackageSpec struct {
+	// Dir is the name of the package directory. By default, it's the package name
+	// or name=version when the version is pinned. The name without the version makes the pinned version
+	// not essential, so that the package can be replaced when fixing the bundle.
+	Dir  string `json:"dir"`
+	Name string `json:"name"`
+	// Version pins the version of the main package. Empty version means the latest one.
+	Version string `json:"version"`
+	// Extra are additional packages to put into the package directory, either name or name=version.
+	Extra []string `json:"extra"`
+}
+
+// validate checks that the main package can be found in the package directory by its name and version.
+func (s PackageSpec) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("package has no name")
+	}
+	if s.Dir != "" && s.Dir != s.Name && !strings.HasPrefix(s.Dir, s.Name+"=") {
+		return fmt.Errorf("directory %s of package %s is neither the package name nor name=version, so the "+
+			"main package cannot be found in it", s.Dir, s.Name)
+	}
+	if dirVersion := strings.TrimPrefix(s.Dir, s.Name+"="); dirVersion != s.Dir && dirVersion != s.Version {
+		return fmt.Errorf("directory %s of package %s pins version %s, but the package version is \"%s\", so "+
+			"the main package cannot be found in it", s.Dir, s.Name, dirVersion, s.Version)
+	}
+	return nil
+}
+
+// DirName returns the name of the package directory.
+func (s PackageSpec) DirName() string {
+	if s.Dir != "" {
+		return s.Dir
+	}
+	if s.Version != "" {
+		return s.Name + "=" + s.Version
+	}
+	return s.Name
+}
+
+func ReadSpec(r io.Reader) (Spec, error) {
+	var spec Spec
+	if err := json.NewDecoder(r).Decode(&spec); err != nil {
+		return spec, fmt.Errorf("cannot parse bundle specification. Error: %w", err)
+	}
+	dirs := make(map[string]bool)
+	for i, ps := range spec.Packages {
+		if err := ps.validate(); err != nil {
+			return spec, fmt.Errorf("package #%d of the bundle specification is invalid. Error: %w", i+1, err)
+		}
+		if dirs[ps.DirName()] {
+			return spec, fmt.Errorf("more than one package with directory %s found in the bundle specification",
+				ps.DirName())
+		}
+		dirs[ps.DirName()] = true
+	}
+	return spec, nil
+}
+
+// NewBundleFromSpec downloads the packages described by the specification together with their dependencies
+// and creates the bundle from them.
+func NewBundleFromSpec(ctx context.Context, spec Spec, manager PackageManager) (*Bundle, error) {
+	b := &Bundle{Manager: manager}
+	b.Packages = make([]*Package, 0, len(spec.Packages))
+	for _, ps := range spec.Packages {
+		if err := ps.validate(); err != nil {
+			return nil, err
+		}
+		set := []NameVersion{{Name: ps.Name, Version: ps.Version}}
+		for _, e := range ps.Extra {
+			nv, err := manager.ParseNameVersion(e)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse extra package %s of %s. Error: %w", e, ps.Name, err)
+			}
+			set = append(set, nv)
+		}
+		p, err := manager.DownloadSet(ctx, ps.DirName(), set)
+		if err != nil {
+			return nil, fmt.Errorf("cannot download package %s. Error: %w", ps.Name, err)
+		}
+		b.Packages = append(b.Packages, p)
+		if b.Architecture, err = commonArchitecture(b.Architecture, p); err != nil {
+			return nil, fmt.Errorf("bundle contains packages of different architectures. Error: %w", err)
+		}
+	}
+	return b, nil
+}
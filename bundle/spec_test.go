@@ -0,0 +1,117 @@
+package bundle
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		wantDirs []string
+		wantErr  bool
+	}{
+		{
+			name: "reads specification",
+			spec: `{"packages": [
+				{"name": "chrony"},
+				{"name": "kubeadm", "version": "1.20.11-00", "extra": ["kubernetes-cni=0.8.7-00"]},
+				{"dir": "nvidia-container-runtime", "name": "nvidia-container-runtime", "version": "3.5.0-1"},
+				{"dir": "containerd.io=1.4.7-1", "name": "containerd.io", "version": "1.4.7-1"}
+			]}`,
+			wantDirs: []string{"chrony", "kubeadm=1.20.11-00", "nvidia-container-runtime", "containerd.io=1.4.7-1"},
+		},
+		{
+			name:    "rejects package without name",
+			spec:    `{"packages": [{"version": "1.20.11-00"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "rejects directory without package name",
+			spec:    `{"packages": [{"dir": "time-sync", "name": "chrony"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "rejects directory with another version",
+			spec:    `{"packages": [{"dir": "chrony=3.2", "name": "chrony", "version": "3.5"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "rejects duplicated directories",
+			spec:    `{"packages": [{"name": "chrony"}, {"dir": "chrony", "name": "chrony", "version": "3.5"}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ReadSpec(strings.NewReader(tt.spec))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			dirs := make([]string, len(spec.Packages))
+			for i, ps := range spec.Packages {
+				dirs[i] = ps.DirName()
+			}
+			if !reflect.DeepEqual(dirs, tt.wantDirs) {
+				t.Errorf("DirName() = %v, want %v", dirs, tt.wantDirs)
+			}
+		})
+	}
+}
+
+func TestNewBundleFromSpec(t *testing.T) {
+	m := &fakeManager{downloads: map[string][]string{
+		"chrony=3.5":       {"chrony_3.5_amd64.deb", "libseccomp2_2.5_amd64.deb"},
+		"kubeadm=1.20 cni": {"kubeadm_1.20_amd64.deb", "cni_0.8_amd64.deb"},
+	}}
+	tests := []struct {
+		name          string
+		spec          PackageSpec
+		wantEssential bool
+		wantErr       bool
+	}{
+		{
+			name:          "downloads pinned version into default directory",
+			spec:          PackageSpec{Name: "chrony", Version: "3.5"},
+			wantEssential: true,
+		},
+		{
+			name: "downloads pinned version into directory without version",
+			spec: PackageSpec{Dir: "chrony", Name: "chrony", Version: "3.5"},
+		},
+		{
+			name:          "downloads extra packages",
+			spec:          PackageSpec{Dir: "kubeadm=1.20", Name: "kubeadm", Version: "1.20", Extra: []string{"cni"}},
+			wantEssential: true,
+		},
+		{
+			name:    "rejects directory without package name",
+			spec:    PackageSpec{Dir: "time-sync", Name: "chrony", Version: "3.5"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewBundleFromSpec(context.Background(), Spec{Packages: []PackageSpec{tt.spec}}, m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewBundleFromSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			p := b.Packages[0]
+			if p.Name != tt.spec.Name || p.Version != tt.spec.Version || p.VersionEssential != tt.wantEssential ||
+				len(p.Dependencies) != 1 || path.Dir(p.Path) != tt.spec.DirName() {
+				t.Errorf("NewBundleFromSpec() package = %+v, want %s - v%s in %s, essential %v, with a dependency",
+					p, tt.spec.Name, tt.spec.Version, tt.spec.DirName(), tt.wantEssential)
+			}
+		})
+	}
+}
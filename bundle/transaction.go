@@ -0,0 +1,78 @@
+package bundle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Transaction is the plan of a simulated installation: what the package manager would do to the node.
+type Transaction struct {
+	Installs   []Change
+	Upgrades   []Change
+	Downgrades []Change
+	Reinstalls []Change
+	Removals   []Change
+	// ConfigureOrder lists the packages in the order the package manager would configure them.
+	ConfigureOrder []string
+}
+
+// Change is a change of a single package in a transaction.
+type Change struct {
+	Name string
+	// OldVersion is the installed version. It's empty for new packages.
+	OldVersion string
+	// NewVersion is the version to install. It's empty for removals.
+	NewVersion string
+	// Origin is where the new version comes from, for example "Ubuntu:18.04/bionic-updates" or "local-deb".
+	Origin       string
+	Architecture string
+}
+
+func (c Change) String() string {
+	switch {
+	case c.NewVersion == "":
+		return fmt.Sprintf("%s %s", c.Name, c.OldVersion)
+	case c.OldVersion == "" || c.OldVersion == c.NewVersion:
+		return fmt.Sprintf("%s %s (%s)", c.Name, c.NewVersion, c.Origin)
+	default:
+		return fmt.Sprintf("%s %s -> %s (%s)", c.Name, c.OldVersion, c.NewVersion, c.Origin)
+	}
+}
+
+// Empty returns true if the transaction changes nothing.
+func (t Transaction) Empty() bool {
+	return len(t.Installs)+len(t.Upgrades)+len(t.Downgrades)+len(t.Reinstalls)+len(t.Removals) == 0
+}
+
+// Summary returns the number of changes of every kind.
+func (t Transaction) Summary() string {
+	return fmt.Sprintf("%d to install, %d to upgrade, %d to downgrade, %d to reinstall, %d to remove",
+		len(t.Installs), len(t.Upgrades), len(t.Downgrades), len(t.Reinstalls), len(t.Removals))
+}
+
+func (t Transaction) String() string {
+	lines := make([]string, 0, 6)
+	for _, group := range []struct {
+		title   string
+		changes []Change
+	}{
+		{"Install", t.Installs},
+		{"Upgrade", t.Upgrades},
+		{"Downgrade", t.Downgrades},
+		{"Reinstall", t.Reinstalls},
+		{"Remove", t.Removals},
+	} {
+		if len(group.changes) == 0 {
+			continue
+		}
+		changes := make([]string, len(group.changes))
+		for i, c := range group.changes {
+			changes[i] = c.String()
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", group.title, strings.Join(changes, ", ")))
+	}
+	if len(t.ConfigureOrder) > 0 {
+		lines = append(lines, "Configure order: "+strings.Join(t.ConfigureOrder, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
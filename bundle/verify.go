@@ -0,0 +1,86 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// VerifyResult is the result of simulated installation of every bundle package and the whole bundle.
+type VerifyResult struct {
+	Packages []InstallResult
+	Bundle   InstallResult
+}
+
+// VerifyBundle simulates installation of every package of the bundle with its dependencies and then of all
+// the packages together. It does not fix anything, so use a package manager without package sources, so that only
+// the bundle and the installed packages satisfy the dependencies.
+func VerifyBundle(ctx context.Context, b *Bundle) (*VerifyResult, error) {
+	res := &VerifyResult{Packages: make([]InstallResult, len(b.Packages))}
+	for i, p := range b.Packages {
+		r, err := b.Manager.CheckInstall(ctx, p)
+		if err != nil {
+			return nil, fmt.Errorf("cannot simulate installation of package %s. Error: %w", p.Path, err)
+		}
+		res.Packages[i] = r
+	}
+	r, err := b.Manager.CheckInstallAll(ctx, b.Packages)
+	if err != nil {
+		return nil, fmt.Errorf("cannot simulate installation of the whole bundle. Error: %w", err)
+	}
+	res.Bundle = r
+	return res, nil
+}
+
+// OK returns true if every package and the whole bundle can be installed without removing installed packages.
+func (r *VerifyResult) OK() bool {
+	if !installedCleanly(r.Bundle) {
+		return false
+	}
+	for _, pr := range r.Packages {
+		if !installedCleanly(pr) {
+			return false
+		}
+	}
+	return true
+}
+
+func installedCleanly(r InstallResult) bool {
+	return r.Result == ResultOk && len(r.Transaction.Removals) == 0
+}
+
+func (r *VerifyResult) String() string {
+	lines := make([]string, 0, len(r.Packages)+1)
+	for _, pr := range r.Packages {
+		lines = append(lines, verifyLine(fmt.Sprintf("%s - v%s", pr.Package.Name, pr.Package.Version), pr))
+	}
+	lines = append(lines, verifyLine("The whole bundle", r.Bundle))
+	return strings.Join(lines, "\n")
+}
+
+func verifyLine(name string, r InstallResult) string {
+	if installedCleanly(r) {
+		return fmt.Sprintf("%s: OK (%s)", name, r.Transaction.Summary())
+	}
+	if r.Result == ResultOk {
+		removals := make([]string, len(r.Transaction.Removals))
+		for i, c := range r.Transaction.Removals {
+			removals[i] = c.String()
+		}
+		return fmt.Sprintf("%s: FAILED. Reason:\n    installation removes %s", name, strings.Join(removals, ", "))
+	}
+	return fmt.Sprintf("%s: FAILED. Reason:\n%s", name, aptReason(r.Output))
+}
+
+// aptReason drops the progress and transaction lines from the package manager output.
+func aptReason(output string) string {
+	lines := make([]string, 0)
+	for _, l := range strings.Split(output, "\n") {
+		if l == "" || strings.HasPrefix(l, "Reading ") || strings.HasPrefix(l, "Building ") ||
+			strings.HasPrefix(l, "Inst ") || strings.HasPrefix(l, "Conf ") {
+			continue
+		}
+		lines = append(lines, "    "+l)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,73 @@
+package bundle
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestVerifyBundle(t *testing.T) {
+	ok := InstallResult{Result: ResultOk, Transaction: Transaction{Installs: []Change{{Name: "chrony"}}}}
+	removal := InstallResult{Result: ResultOk, Transaction: Transaction{
+		Removals: []Change{{Name: "ntp", OldVersion: "4.2"}},
+	}}
+	unmet := InstallResult{Result: ResultUnmetDependencies, Output: "chrony : Depends: libseccomp2 but it is " +
+		"not installable"}
+	tests := []struct {
+		name     string
+		installs map[string]InstallResult
+		want     bool
+		wantLine string
+	}{
+		{
+			name:     "installs bundle",
+			installs: map[string]InstallResult{"chrony": ok, "kubelet": ok, "chrony kubelet": ok},
+			want:     true,
+			wantLine: "The whole bundle: OK",
+		},
+		{
+			name:     "fails on a dependency missing from the bundle",
+			installs: map[string]InstallResult{"chrony": unmet, "kubelet": ok, "chrony kubelet": unmet},
+			wantLine: "chrony - v3.2: FAILED. Reason:\n    chrony : Depends: libseccomp2",
+		},
+		{
+			name:     "fails on removals",
+			installs: map[string]InstallResult{"chrony": ok, "kubelet": ok, "chrony kubelet": removal},
+			wantLine: "The whole bundle: FAILED. Reason:\n    installation removes ntp 4.2",
+		},
+		{
+			name:     "fails on removals of a single package",
+			installs: map[string]InstallResult{"chrony": ok, "kubelet": removal, "chrony kubelet": ok},
+			wantLine: "kubelet - v1.20: FAILED",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := newFakeBundle(&fakeManager{installs: tt.installs},
+				"chrony/chrony_3.2_amd64.deb", "kubelet/kubelet_1.20_amd64.deb")
+			if err != nil {
+				t.Fatalf("newFakeBundle() error = %v", err)
+			}
+			res, err := VerifyBundle(context.Background(), b)
+			if err != nil {
+				t.Fatalf("VerifyBundle() error = %v", err)
+			}
+			if got := res.OK(); got != tt.want {
+				t.Errorf("VerifyBundle() OK = %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(res.String(), tt.wantLine) {
+				t.Errorf("VerifyBundle() = %q, want it to contain %q", res.String(), tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestVerifyBundle_Error(t *testing.T) {
+	b, err := newFakeBundle(&fakeManager{}, "chrony/chrony_3.2_amd64.deb")
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	if _, err = VerifyBundle(context.Background(), b); err == nil {
+		t.Errorf("VerifyBundle() error = nil, want an error")
+	}
+}
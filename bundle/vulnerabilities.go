@@ -0,0 +1,153 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Advisory is a security advisory about the versions of a package that are affected by known vulnerabilities.
+type Advisory struct {
+	// ID identifies the advisory, for example USN-4738-1 or CVE-2021-23840.
+	ID       string
+	CVEs     []string
+	Severity string
+	// Package is the binary package name, or the source package name when Source is true.
+	Package string
+	Source  bool
+	// FixedVersion is the first version that is not affected. Empty means that no fix is available.
+	FixedVersion string
+}
+
+// Affects returns true if the package version is affected by the advisory.
+func (a Advisory) Affects(ctx context.Context, m PackageManager, p *Package) (bool, error) {
+	name, version := p.Name, p.Version
+	if a.Source {
+		c, err := p.Control(ctx)
+		if err != nil {
+			return false, err
+		}
+		name, version = c.Source, c.SourceVersion
+	}
+	if name != a.Package {
+		return false, nil
+	}
+	return a.FixedVersion == "" || m.CompareVersions(version, a.FixedVersion) < 0, nil
+}
+
+// Vulnerability is a bundle package affected by an advisory.
+type Vulnerability struct {
+	Package  *Package
+	Advisory Advisory
+}
+
+func (v Vulnerability) String() string {
+	s := fmt.Sprintf("%s - v%s (%s): %s", v.Package.Name, v.Package.Version, v.Package.Path, v.Advisory.ID)
+	if len(v.Advisory.CVEs) > 0 && (len(v.Advisory.CVEs) > 1 || v.Advisory.CVEs[0] != v.Advisory.ID) {
+		s += " (" + strings.Join(v.Advisory.CVEs, ", ") + ")"
+	}
+	if v.Advisory.Severity != "" {
+		s += ", " + strings.ToLower(v.Advisory.Severity)
+	}
+	if v.Advisory.FixedVersion == "" {
+		return s + ", no fixed version available"
+	}
+	return s + ", fixed in " + v.Advisory.FixedVersion
+}
+
+// FindVulnerabilities matches all the bundle packages and dependencies against the advisories.
+func FindVulnerabilities(ctx context.Context, b *Bundle, advisories []Advisory) ([]Vulnerability, error) {
+	vulnerabilities := make([]Vulnerability, 0)
+	for _, p := range uniquePackages(b) {
+		found, err := packageVulnerabilities(ctx, b, p, advisories)
+		if err != nil {
+			return nil, err
+		}
+		vulnerabilities = append(vulnerabilities, found...)
+	}
+	return vulnerabilities, nil
+}
+
+func packageVulnerabilities(ctx context.Context, b *Bundle, p *Package,
+	advisories []Advisory) ([]Vulnerability, error) {
+	var vulnerabilities []Vulnerability
+	for _, a := range advisories {
+		affected, err := a.Affects(ctx, b.Manager, p)
+		if err != nil {
+			return nil, err
+		}
+		if affected {
+			vulnerabilities = append(vulnerabilities, Vulnerability{Package: p, Advisory: a})
+		}
+	}
+	return vulnerabilities, nil
+}
+
+// fixedVersions splits the versions of the package to the versions that are not affected by the advisories
+// and the affected ones, keeping the order.
+func fixedVersions(ctx context.Context, b *Bundle, p *Package, versions []string) ([]string, []string, error) {
+	var fixed, affected []string
+	for _, v := range versions {
+		candidate := &Package{NameVersion: NameVersion{Name: p.Name, Version: v}}
+		vulnerabilities, err := packageVulnerabilities(ctx, b, candidate, binaryAdvisories(ctx, b, p))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(vulnerabilities) == 0 {
+			fixed = append(fixed, v)
+		} else {
+			affected = append(affected, v)
+		}
+	}
+	return fixed, affected, nil
+}
+
+// binaryAdvisories returns the advisories about the package with the source advisories turned into binary ones,
+// so that they apply to other versions of the package without reading their control information. It assumes
+// that the binary versions follow the source versions.
+func binaryAdvisories(ctx context.Context, b *Bundle, p *Package) []Advisory {
+	c, err := p.Control(ctx)
+	advisories := make([]Advisory, 0)
+	for _, a := range b.Advisories {
+		switch {
+		case !a.Source && a.Package == p.Name:
+			advisories = append(advisories, a)
+		case a.Source && err == nil && a.Package == c.Source:
+			a.Source = false
+			a.Package = p.Name
+			advisories = append(advisories, a)
+		}
+	}
+	return advisories
+}
+
+// preferFixedVersion replaces the installable package affected by the advisories with the newest installable
+// version that is not affected. It returns false when there is no such version and the package stays as it is.
+func preferFixedVersion(ctx context.Context, p *Package, b *Bundle, res *FixResult) (bool, error) {
+	vulnerabilities, err := packageVulnerabilities(ctx, b, p, b.Advisories)
+	if err != nil {
+		res.AddLog("Couldn't match the package against the security advisories due to the following error: " +
+			err.Error())
+		return false, err
+	}
+	if len(vulnerabilities) == 0 {
+		return false, nil
+	}
+	lines := make([]string, len(vulnerabilities))
+	for i, v := range vulnerabilities {
+		lines[i] = v.String()
+	}
+	res.AddLog("The package is affected by known vulnerabilities:\n" + strings.Join(lines, "\n") +
+		"\nI'm going to replace it with a fixed version.")
+	// The package stays installable when no fixed version fits, so the violations of the rejected candidates
+	// are not failures of the package.
+	violations := len(res.Violations)
+	if _, err = searchVersions(ctx, p, b, res, true); err != nil {
+		return false, err
+	}
+	if !res.Success {
+		res.Violations = res.Violations[:violations]
+		res.AddLog("I couldn't replace the package with a fixed version, so I'm going to keep the current one.")
+	}
+	return res.Success, nil
+}
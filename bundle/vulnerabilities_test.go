@@ -0,0 +1,42 @@
+package bundle
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFindVulnerabilities(t *testing.T) {
+	m := &fakeManager{controls: map[string]*Control{
+		"libssl1.1": {Source: "openssl", SourceVersion: "1.1.1-1"},
+		"openssl":   {Source: "openssl", SourceVersion: "1.1.1-3"},
+	}}
+	b, err := newFakeBundle(m,
+		"openssl/openssl_1.1.1-3_amd64.deb",
+		"openssl/libssl1.1_1.1.1-1_amd64.deb",
+		"chrony/chrony_3.2_amd64.deb",
+	)
+	if err != nil {
+		t.Fatalf("newFakeBundle() error = %v", err)
+	}
+	advisories := []Advisory{
+		{ID: "CVE-2021-23840", Package: "openssl", Source: true, FixedVersion: "1.1.1-2"},
+		{ID: "USN-4000-1", CVEs: []string{"CVE-2020-0001"}, Package: "chrony"},
+		{ID: "USN-4001-1", Package: "chrony", FixedVersion: "3.1"},
+	}
+	got, err := FindVulnerabilities(context.Background(), b, advisories)
+	if err != nil {
+		t.Fatalf("FindVulnerabilities() error = %v", err)
+	}
+	want := []string{
+		"chrony - v3.2 (chrony/chrony_3.2_amd64.deb): USN-4000-1 (CVE-2020-0001), no fixed version available",
+		"libssl1.1 - v1.1.1-1 (openssl/libssl1.1_1.1.1-1_amd64.deb): CVE-2021-23840, fixed in 1.1.1-2",
+	}
+	lines := make([]string, len(got))
+	for i, v := range got {
+		lines[i] = v.String()
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("FindVulnerabilities() = %v, want %v", lines, want)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"konvoy-os-package-builder/bundle"
+	"konvoy-os-package-builder/pkg/apt"
+)
+
+// workDirSuffix is appended to the output tarball path to get the default work directory of the checkpoints.
+const workDirSuffix = ".work"
+
+// openCheckpoint opens the checkpoint of the run in the work directory. The checkpoint is kept while the inputs
+// stay the same: the bundle files, the fix options, the package sources and architecture, and the tool version.
+func openCheckpoint(b *bundle.Bundle, m *apt.Manager, opts bundle.FixOptions,
+	workDir string) (*bundle.DirCheckpoint, error) {
+	sources, err := m.Sources()
+	if err != nil {
+		return nil, err
+	}
+	extra := append([]string{"architecture=" + m.Architecture(), "version=" + version}, sources...)
+	key, err := bundle.CheckpointKey(b, opts, extra...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot identify the inputs of the run. Error: %w", err)
+	}
+	return bundle.OpenCheckpoint(workDir, key, m)
+}
@@ -3,11 +3,19 @@ package main
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
+	"os/signal"
 	"path"
+	"strings"
+	"syscall"
+	"time"
 
 	"konvoy-os-package-builder/bundle"
 	"konvoy-os-package-builder/pkg/apt"
@@ -16,23 +24,489 @@ import (
 )
 
 func main() {
-	f, err := os.Open("backup_konvoy_v1.8.3_amd64_debs.tar.gz")
+	input := flag.String("input", "backup_konvoy_v1.8.3_amd64_debs.tar.gz", "original OS package bundle")
+	output := flag.String("output", "konvoy_v1.8.3_amd64_debs.tar.gz", "fixed OS package bundle to create")
+	arch := flag.String("arch", "", "architecture of the bundle packages, the host architecture by default")
+	targetsPath := flag.String("targets", "", "JSON file with the list of OS releases to build the bundle for")
+	baseImage := flag.String("base-image", "", "dpkg status file or package list of the base image to prune "+
+		"the dependencies the nodes already have")
+	specPath := flag.String("spec", "", "JSON file with the bundle specification to build the bundle from "+
+		"instead of the original bundle")
+	dotPath := flag.String("dot", "", "file to write the bundle dependency graph in Graphviz DOT format to")
+	mermaidPath := flag.String("mermaid", "", "file to write the bundle dependency graph in Mermaid format to")
+	spdxPath := flag.String("spdx", "", "file to write the SPDX SBOM of the fixed bundle to")
+	cycloneDXPath := flag.String("cyclonedx", "", "file to write the CycloneDX SBOM of the fixed bundle to")
+	reportPath := flag.String("report", "", "file to write the HTML report of the fix run to")
+	autoAccept := flag.Bool("auto-accept", false, "accept all replacements without asking even if the input "+
+		"is a terminal")
+	advisoriesPath := flag.String("advisories", "", "Ubuntu CVE OVAL XML or USN database JSON file to match the "+
+		"bundle packages against")
+	release := flag.String("release", "", "distro release to read the USN advisories for, the host release by default")
+	preferFixed := flag.Bool("prefer-fixed", false, "replace the packages affected by the advisories with fixed "+
+		"versions when their versions are not essential")
+	policyPath := flag.String("policy", "", "JSON file with the policy that restricts the fixes")
+	provenancePath := flag.String("provenance", "", "file to write the provenance of the fixed bundle to, "+
+		"the output path with the "+provenanceSuffix+" suffix by default")
+	quarantineDir := flag.String("quarantine", "", "directory to move the corrupt package files to, "+
+		"the output path with the "+quarantineSuffix+" suffix by default")
+	timeout := flag.Duration("timeout", 0, "time limit of the whole run, for example 2h, no limit by default")
+	operationTimeout := flag.Duration("operation-timeout", 0, "time limit of every package manager operation, "+
+		"for example a simulated installation or a download, no limit by default")
+	workDir := flag.String("work-dir", "", "directory to save the outcome of every fixed package to, so that "+
+		"a stopped run continues from where it stopped, the output path with the "+workDirSuffix+" suffix by default")
+	progressMode := flag.String("progress", progressAuto, "how to show the progress of the run: "+progressBar+
+		" on a terminal, "+progressLines+" for logs, "+progressNone+" or "+progressAuto+" to choose by the output")
+	verifyPath := flag.String("verify", "", "fixed OS package bundle to verify on the -base-image "+
+		"packages instead of fixing the original one")
+	flag.Parse()
+	// Ctrl-C stops the run and the launched package manager processes, so that the temporary files are removed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	if *verifyPath != "" {
+		// Only the bundle and the base image packages may satisfy the dependencies, so the root has no sources.
+		// Without the base image packages, even libc6 would be missing on the clean node.
+		if *baseImage == "" {
+			log.Fatalln("-verify needs -base-image with the dpkg status file of the nodes the bundle is installed on")
+		}
+		status, err := readInstalledStatus(*baseImage)
+		must(err)
+		cfg := apt.Config{Architecture: *arch, Offline: true, Status: status, OperationTimeout: *operationTimeout}
+		ok, err := verifyBundle(ctx, *verifyPath, cfg)
+		must(err)
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+	progress, err := newProgressRenderer(*progressMode)
+	must(err)
+	var policy bundle.Policy
+	if *policyPath != "" {
+		policy, err = readPolicy(*policyPath)
+		must(err)
+	}
+	var approver bundle.Approver
+	if !*autoAccept && isTerminal(os.Stdin) {
+		approver = newTerminalApprover(os.Stdin, os.Stdout, progress)
+	}
+	provenance := provenanceConfig{path: *provenancePath, parameters: make(map[string]string)}
+	flag.Visit(func(f *flag.Flag) {
+		provenance.parameters[f.Name] = f.Value.String()
+	})
+	for _, f := range []string{*specPath, *policyPath, *advisoriesPath, *targetsPath} {
+		if f != "" {
+			provenance.materials = append(provenance.materials, f)
+		}
+	}
+	var source bundleSource
+	if *specPath != "" {
+		spec, err := readSpec(*specPath)
+		must(err)
+		source = specSource(spec)
+	} else {
+		fileSystem, err := readTarball(*input)
+		must(err)
+		source = tarballSource(fileSystem)
+		provenance.materials = append([]string{*input}, provenance.materials...)
+	}
+	if *targetsPath == "" {
+		_, err := fixBundle(ctx, source, buildConfig{
+			apt:       apt.Config{Architecture: *arch, OperationTimeout: *operationTimeout},
+			baseImage: *baseImage,
+			policy:    policy,
+			approver:  approver,
+			output:    *output,
+			dot:       *dotPath,
+			mermaid:   *mermaidPath,
+			report:    *reportPath,
+			spdx:      *spdxPath,
+			cyclonedx: *cycloneDXPath,
+			vulnerabilities: vulnerabilityConfig{
+				advisories:  *advisoriesPath,
+				release:     *release,
+				preferFixed: *preferFixed,
+			},
+			provenance: provenance,
+			quarantine: *quarantineDir,
+			progress:   progress,
+			workDir:    *workDir,
+		})
+		must(err)
+		return
+	}
+	targets, err := readTargets(*targetsPath, *arch)
 	must(err)
+	if !buildTargets(ctx, source, targets, buildConfig{
+		apt:             apt.Config{OperationTimeout: *operationTimeout},
+		policy:          policy,
+		approver:        approver,
+		output:          *output,
+		dot:             *dotPath,
+		mermaid:         *mermaidPath,
+		report:          *reportPath,
+		spdx:            *spdxPath,
+		cyclonedx:       *cycloneDXPath,
+		vulnerabilities: vulnerabilityConfig{advisories: *advisoriesPath, preferFixed: *preferFixed},
+		provenance:      provenance,
+		quarantine:      *quarantineDir,
+		progress:        progress,
+		workDir:         *workDir,
+	}) {
+		os.Exit(1)
+	}
+}
+
+func readTarball(tarBallPath string) (fs.FS, error) {
+	f, err := os.Open(tarBallPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %s. Error: %w", tarBallPath, err)
+	}
 	//noinspection GoUnhandledErrorResult
 	defer f.Close()
 	gzr, err := gzip.NewReader(f)
-	must(err)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress file %s. Error: %w", tarBallPath, err)
+	}
 	//noinspection GoUnhandledErrorResult
 	defer gzr.Close()
 	fileSystem, err := tarfs.New(gzr)
-	must(err)
-	m, err := apt.NewManager()
-	must(err)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tarball %s. Error: %w", tarBallPath, err)
+	}
+	return fileSystem, nil
+}
+
+func readPolicy(policyPath string) (bundle.Policy, error) {
+	f, err := os.Open(policyPath)
+	if err != nil {
+		return bundle.Policy{}, fmt.Errorf("cannot open file %s. Error: %w", policyPath, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	return bundle.ReadPolicy(f)
+}
+
+func readAdvisories(advisoriesPath, release string) ([]bundle.Advisory, error) {
+	if release == "" {
+		release = hostRelease()
+	}
+	f, err := os.Open(advisoriesPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %s. Error: %w", advisoriesPath, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	advisories, err := apt.ReadAdvisories(f, release)
+	if errors.Is(err, apt.ErrReleaseRequired) {
+		return nil, fmt.Errorf("the host release is unknown, please set the release of the advisories with "+
+			"-release or the release field of the target. Error: %w", err)
+	}
+	return advisories, err
+}
+
+// hostRelease returns the codename of the host distro release. It returns an empty string when it's unknown.
+func hostRelease() string {
+	return osReleaseField("VERSION_CODENAME")
+}
+
+// osReleaseField returns the field of the host /etc/os-release file or an empty string if it's unknown.
+func osReleaseField(name string) string {
+	data, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, name+"=") {
+			return strings.Trim(strings.TrimPrefix(line, name+"="), `"`)
+		}
+	}
+	return ""
+}
+
+func readSpec(specPath string) (bundle.Spec, error) {
+	f, err := os.Open(specPath)
+	if err != nil {
+		return bundle.Spec{}, fmt.Errorf("cannot open file %s. Error: %w", specPath, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	return bundle.ReadSpec(f)
+}
+
+// bundleSource creates the bundle to fix with the package manager.
+type bundleSource func(ctx context.Context, m bundle.PackageManager) (*bundle.Bundle, error)
+
+// tarballSource repairs the existing bundle read from a tarball.
+func tarballSource(fileSystem fs.FS) bundleSource {
+	return func(_ context.Context, m bundle.PackageManager) (*bundle.Bundle, error) {
+		return bundle.NewBundle(fileSystem, m)
+	}
+}
+
+// specSource builds the bundle from the specification.
+func specSource(spec bundle.Spec) bundleSource {
+	return func(ctx context.Context, m bundle.PackageManager) (*bundle.Bundle, error) {
+		return bundle.NewBundleFromSpec(ctx, spec, m)
+	}
+}
+
+// buildConfig configures fixing of a single bundle.
+type buildConfig struct {
+	apt apt.Config
+	// baseImage is the dpkg status file or the package list of the base image. Empty means no pruning.
+	baseImage string
+	policy    bundle.Policy
+	// approver approves the replacements. Nil means that all replacements are accepted.
+	approver bundle.Approver
+	output   string
+	// dot and mermaid are the files to write the dependency graph to. Empty means no graph.
+	dot     string
+	mermaid string
+	// report is the file to write the HTML report to. Empty means no report.
+	report string
+	// spdx and cyclonedx are the files to write the SBOM to. Empty means no SBOM.
+	spdx            string
+	cyclonedx       string
+	vulnerabilities vulnerabilityConfig
+	provenance      provenanceConfig
+	// quarantine is the directory to move the corrupt package files to. Empty means the output tarball path
+	// with quarantineSuffix.
+	quarantine string
+	// progress renders the progress of the run. Nil means no progress.
+	progress progressRenderer
+	// workDir is the directory of the checkpoints. Empty means the output tarball path with workDirSuffix.
+	workDir string
+}
+
+// vulnerabilityConfig configures matching of the bundle packages against the security advisories.
+type vulnerabilityConfig struct {
+	// advisories is the OVAL or USN file. Empty means no matching.
+	advisories string
+	// release is the distro release of the USN advisories. Empty means the host release.
+	release     string
+	preferFixed bool
+}
+
+// fixBundle checks and fixes the bundle from the source and writes the result to the output tarball.
+func fixBundle(ctx context.Context, source bundleSource, cfg buildConfig) (*bundle.BundleFixResult, error) {
+	started := time.Now()
+	m, err := apt.NewManagerWithConfig(ctx, cfg.apt)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := m.Clean(); err != nil {
+			log.Println(err)
+		}
+	}()
+	b, err := source(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	quarantineDir := cfg.quarantine
+	if quarantineDir == "" {
+		quarantineDir = cfg.output + quarantineSuffix
+	}
+	if err = quarantinePackages(ctx, b, quarantineDir); err != nil {
+		return nil, err
+	}
+	opts := bundle.FixOptions{Policy: cfg.policy, Approver: cfg.approver}
+	if cfg.vulnerabilities.advisories != "" {
+		opts.Advisories, err = readAdvisories(cfg.vulnerabilities.advisories,
+			cfg.vulnerabilities.release)
+		if err != nil {
+			return nil, err
+		}
+		opts.PreferFixedVersions = cfg.vulnerabilities.preferFixed
+	}
+	if cfg.baseImage != "" {
+		if opts.Installed, err = readInstalledPackages(cfg.baseImage); err != nil {
+			return nil, err
+		}
+	}
+	if b.Architecture != "" && b.Architecture != m.Architecture() {
+		return nil, fmt.Errorf("the bundle architecture is %s, but the packages are resolved for %s. "+
+			"Please, set the bundle architecture", b.Architecture, m.Architecture())
+	}
+	if cfg.progress != nil {
+		opts.Progress = cfg.progress
+	}
+	workDir := cfg.workDir
+	if workDir == "" {
+		workDir = cfg.output + workDirSuffix
+	}
+	checkpoint, err := openCheckpoint(b, m, opts, workDir)
+	if err != nil {
+		return nil, err
+	}
+	opts.Checkpoint = checkpoint
+	res, err := bundle.Fix(ctx, b, opts)
+	if cfg.progress != nil {
+		cfg.progress.Finish()
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.Print(os.Stdout)
+	b = res.Bundle
+	if err = verifyPackages(ctx, b, quarantineDir); err != nil {
+		return nil, err
+	}
+	if err = bundleToTarball(b, cfg.output); err != nil {
+		return nil, err
+	}
+	if err = writeProvenance(b, m, cfg, started); err != nil {
+		return nil, err
+	}
+	if err = writeGraph(ctx, b, res.Unresolved, cfg.dot, cfg.mermaid); err != nil {
+		return nil, err
+	}
+	if err = writeSBOM(ctx, b, m, path.Base(cfg.output), cfg.spdx, cfg.cyclonedx); err != nil {
+		return nil, err
+	}
+	if cfg.report != "" {
+		report, err := bundle.NewReport(b, res)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create the report. Error: %w", err)
+		}
+		if err = writeFile(cfg.report, report.WriteHTML); err != nil {
+			return nil, err
+		}
+	}
+	// The bundle is complete, so the next run starts from scratch.
+	if err = checkpoint.Remove(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// verifyBundle simulates installation of every package of the bundle tarball and of the whole bundle.
+func verifyBundle(ctx context.Context, tarBallPath string, cfg apt.Config) (bool, error) {
+	fileSystem, err := readTarball(tarBallPath)
+	if err != nil {
+		return false, err
+	}
+	m, err := apt.NewManagerWithConfig(ctx, cfg)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := m.Clean(); err != nil {
+			log.Println(err)
+		}
+	}()
 	b, err := bundle.NewBundle(fileSystem, m)
-	must(err)
-	bundle.CheckAndFixBundle(b)
-	err = bundleToTarball(b, "konvoy_v1.8.3_amd64_debs.tar.gz")
-	must(err)
+	if err != nil {
+		return false, err
+	}
+	corrupt, err := bundle.VerifyPackages(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	if len(corrupt) > 0 {
+		fmt.Printf("The following package files of the bundle %s are corrupt:\n%s\n", tarBallPath,
+			printCorruptPackages(corrupt))
+		return false, nil
+	}
+	res, err := bundle.VerifyBundle(ctx, b)
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("Verification of the bundle %s:\n%s\n", tarBallPath, res)
+	if !res.OK() {
+		fmt.Println("The bundle cannot be installed on a clean node from its own packages.")
+		return false, nil
+	}
+	fmt.Println("The bundle can be installed on a clean node from its own packages.")
+	return true, nil
+}
+
+func writeGraph(ctx context.Context, b *bundle.Bundle, unresolved []string, dotPath, mermaidPath string) error {
+	if dotPath == "" && mermaidPath == "" {
+		return nil
+	}
+	g, err := bundle.NewGraph(ctx, b, unresolved)
+	if err != nil {
+		return fmt.Errorf("cannot build the dependency graph. Error: %w", err)
+	}
+	if dotPath != "" {
+		if err = writeFile(dotPath, g.WriteDOT); err != nil {
+			return err
+		}
+	}
+	if mermaidPath != "" {
+		if err = writeFile(mermaidPath, g.WriteMermaid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSBOM(ctx context.Context, b *bundle.Bundle, m *apt.Manager, name, spdxPath, cycloneDXPath string) error {
+	if spdxPath == "" && cycloneDXPath == "" {
+		return nil
+	}
+	sources, err := m.Sources()
+	if err != nil {
+		return err
+	}
+	sbom, err := bundle.NewSBOM(ctx, b, name, apt.SourcesVendor(sources))
+	if err != nil {
+		return fmt.Errorf("cannot create the SBOM. Error: %w", err)
+	}
+	if spdxPath != "" {
+		if err = writeFile(spdxPath, sbom.WriteSPDX); err != nil {
+			return err
+		}
+	}
+	if cycloneDXPath != "" {
+		if err = writeFile(cycloneDXPath, sbom.WriteCycloneDX); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(filePath string, write func(w io.Writer) error) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("cannot create file %s. Error: %w", filePath, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	if err = write(f); err != nil {
+		return fmt.Errorf("cannot write file %s. Error: %w", filePath, err)
+	}
+	return nil
+}
+
+func readInstalledPackages(baseImage string) (map[string]string, error) {
+	f, err := os.Open(baseImage)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file %s. Error: %w", baseImage, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	return apt.ReadInstalledPackages(f)
+}
+
+func readInstalledStatus(baseImage string) (string, error) {
+	f, err := os.Open(baseImage)
+	if err != nil {
+		return "", fmt.Errorf("cannot open file %s. Error: %w", baseImage, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	status, err := apt.ReadInstalledStatus(f)
+	if err != nil {
+		return "", fmt.Errorf("cannot read base image %s. Error: %w", baseImage, err)
+	}
+	return status, nil
 }
 
 func must(err error) {
@@ -54,16 +528,10 @@ func bundleToTarball(b *bundle.Bundle, tarBallPath string) error {
 	tw := tar.NewWriter(gw)
 	//noinspection GoUnhandledErrorResult
 	defer tw.Close()
-	for _, p := range b.Packages {
-		if err = packageToTarball(p, p.Path, tw); err != nil {
+	for _, f := range b.Files() {
+		if err = packageToTarball(f.Package, f.Path, tw); err != nil {
 			return err
 		}
-		for _, d := range p.Dependencies {
-			dPath := path.Join(path.Base(path.Dir(p.Path)), path.Base(d.Path))
-			if err = packageToTarball(d, dPath, tw); err != nil {
-				return err
-			}
-		}
 	}
 	return nil
 }
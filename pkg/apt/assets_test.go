@@ -56,3 +56,165 @@ The following information may help to resolve the situation:
 The following packages have unmet dependencies.
  chrony : Depends: libtomcrypt0 but it is not installable
 E: Unable to correct problems, you have held broken packages.`
+
+const madisonOutput = `    chrony | 3.2-4ubuntu4.5 | http://archive.ubuntu.com/ubuntu bionic-updates/main amd64 Packages
+    chrony | 3.2-4ubuntu4.5 | http://security.ubuntu.com/ubuntu bionic-security/main amd64 Packages
+    chrony |  3.2-4ubuntu4 | http://archive.ubuntu.com/ubuntu bionic/main amd64 Packages
+    chrony |  3.2-4ubuntu4 | http://archive.ubuntu.com/ubuntu bionic/main Sources`
+
+const dpkgStatus = `Package: socat
+Status: install ok installed
+Priority: extra
+Section: net
+Architecture: amd64
+Version: 1.7.3.2-2ubuntu2
+Description: multipurpose relay for bidirectional data transfer
+ Socat (for SOcket CAT) establishes two bidirectional byte streams and
+ transfers data between them.
+
+Package: libseccomp2
+Status: install ok installed
+Architecture: amd64
+Multi-Arch: same
+Version: 2.4.1-0ubuntu0.18.04.2
+
+Package: conntrack
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1:1.4.4+snapshot20161117-6ubuntu2`
+
+const alternativeDependencies = `Reading package lists...
+Building dependency tree...
+Reading state information...
+Some packages could not be installed. This may mean that you have
+requested an impossible situation or if you are using the unstable
+distribution that some required packages have not yet been created
+or been moved out of Incoming.
+The following information may help to resolve the situation:
+
+The following packages have unmet dependencies.
+ kubelet : Depends: kubernetes-cni (= 0.8.7-00) but 0.9.1-00 is to be installed
+ chrony : Depends: ntp but it is not going to be installed or
+                   time-daemon
+          Breaks: ntp (< 1:4.2.8p12+dfsg-3ubuntu1)
+ cri-tools : PreDepends: libc6 (>= 2.28) but 2.27-3ubuntu1 is to be installed
+E: Unable to correct problems, you have held broken packages.`
+
+const conflictsOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+Some packages could not be installed. This may mean that you have
+requested an impossible situation or if you are using the unstable
+distribution that some required packages have not yet been created
+or been moved out of Incoming.
+The following information may help to resolve the situation:
+
+The following packages have unmet dependencies.
+ containerd.io : Conflicts: containerd
+                 Conflicts: runc
+ docker.io : Breaks: containerd.io (< 1.5)
+E: Unable to correct problems, you have held broken packages.`
+
+const heldPackagesOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+The following held packages will be changed:
+  kubelet kubectl
+The following packages will be upgraded:
+  kubelet kubectl
+2 upgraded, 0 newly installed, 0 to remove and 12 not upgraded.
+E: Held packages were changed and -y was used without --allow-change-held-packages.`
+
+const lockContentionOutput = `E: Could not get lock /var/lib/dpkg/lock-frontend. It is held by process 1234 (apt-get)
+N: Be aware that removing the lock file is not a solution and may break your system.
+E: Unable to acquire the dpkg frontend lock (/var/lib/dpkg/lock-frontend), is another process using it?`
+
+const corruptArchiveOutput = `Reading package lists...
+E: Invalid archive signature
+E: Internal error, could not locate member control.tar.{zstlz4gzxzbz2lzma}
+E: Could not read meta data from /tmp/CheckInstall-chrony-3.2-1/chrony_3.2-4ubuntu4.5_amd64.deb
+E: The package lists or status file could not be parsed or opened.`
+
+const packagesRemovedOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+The following packages will be REMOVED
+  ntp
+The following NEW packages will be installed
+  chrony
+0 to upgrade, 1 to newly install, 1 to remove and 0 not to upgrade.
+E: There are problems and -y was used without --force-yes`
+
+const transactionOutput = `Reading package lists...
+Building dependency tree...
+Reading state information...
+The following packages will be REMOVED
+  ntp
+The following NEW packages will be installed
+  conntrack kubelet kubernetes-cni socat
+The following packages will be upgraded:
+  libseccomp2
+The following packages will be DOWNGRADED:
+  containerd.io
+1 upgraded, 4 newly installed, 1 downgraded, 1 to remove and 0 not upgraded.
+Remv ntp [1:4.2.8p10+dfsg-5ubuntu7.3]
+Inst libseccomp2 [2.4.1-0ubuntu0.18.04.2] (2.5.1-1ubuntu1~18.04.1 Ubuntu:18.04/bionic-updates [amd64]) []
+Inst containerd.io [1.4.9-1] (1.4.7-1 local-deb [amd64])
+Inst conntrack (1:1.4.4+snapshot20161117-6ubuntu2 Ubuntu:18.04/bionic [amd64])
+Inst kubernetes-cni (0.8.7-00 local-deb [amd64])
+Inst socat (1.7.3.2-2ubuntu2 Ubuntu:18.04/bionic, Ubuntu:18.04/bionic-security [amd64])
+Inst kubelet (1.20.11-00 local-deb [amd64])
+Conf libseccomp2 (2.5.1-1ubuntu1~18.04.1 Ubuntu:18.04/bionic-updates [amd64])
+Conf containerd.io (1.4.7-1 local-deb [amd64])
+Conf conntrack (1:1.4.4+snapshot20161117-6ubuntu2 Ubuntu:18.04/bionic [amd64])
+Conf kubernetes-cni (0.8.7-00 local-deb [amd64])
+Conf socat (1.7.3.2-2ubuntu2 Ubuntu:18.04/bionic, Ubuntu:18.04/bionic-security [amd64])
+Conf kubelet (1.20.11-00 local-deb [amd64])`
+
+const ovalAdvisories = `<?xml version="1.0" ?>
+<oval_definitions xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5">
+  <definitions>
+    <definition class="vulnerability" id="oval:com.ubuntu.bionic:def:2021238400000000" version="1">
+      <metadata>
+        <title>CVE-2021-23840 on Ubuntu 18.04 LTS (bionic) - medium.</title>
+        <reference source="CVE" ref_id="CVE-2021-23840" ref_url="https://cve.mitre.org/cgi-bin/cvename.cgi?name=CVE-2021-23840"/>
+        <advisory>
+          <severity>Medium</severity>
+        </advisory>
+      </metadata>
+      <criteria>
+        <extend_definition definition_ref="oval:com.ubuntu.bionic:def:100" comment="Ubuntu 18.04 LTS (bionic) is installed." applicability_check="true" />
+        <criteria operator="OR">
+          <criterion test_ref="oval:com.ubuntu.bionic:tst:2021238400000000" comment="openssl package in bionic was vulnerable but has been fixed (note: '1.1.1-1ubuntu2.1~18.04.8')." />
+          <criterion test_ref="oval:com.ubuntu.bionic:tst:2021238400000010" comment="openssl1.0 package in bionic is affected and needs fixing." />
+        </criteria>
+      </criteria>
+    </definition>
+    <definition class="inventory" id="oval:com.ubuntu.bionic:def:100" version="1">
+      <criteria>
+        <criterion test_ref="oval:com.ubuntu.bionic:tst:100" comment="Ubuntu 18.04 LTS (bionic) is installed." />
+      </criteria>
+    </definition>
+  </definitions>
+</oval_definitions>`
+
+const usnAdvisories = `{
+  "4738-1": {
+    "id": "4738-1",
+    "title": "OpenSSL vulnerabilities",
+    "cves": ["CVE-2021-23840", "CVE-2021-23841"],
+    "releases": {
+      "bionic": {
+        "sources": {"openssl": {"version": "1.1.1-1ubuntu2.1~18.04.8"}},
+        "binaries": {"openssl": {"version": "1.1.1-1ubuntu2.1~18.04.8"}},
+        "allbinaries": {
+          "libssl1.1": {"version": "1.1.1-1ubuntu2.1~18.04.8"},
+          "openssl": {"version": "1.1.1-1ubuntu2.1~18.04.8"}
+        }
+      },
+      "focal": {
+        "sources": {"openssl": {"version": "1.1.1f-1ubuntu2.2"}}
+      }
+    }
+  }
+}`
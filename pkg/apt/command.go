@@ -0,0 +1,97 @@
+package apt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// killGracePeriod is how long a stopped command has to exit after SIGTERM before it's killed.
+const killGracePeriod = 5 * time.Second
+
+// operationContext limits the context with the operation timeout of the manager.
+func (m *Manager) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.operationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, m.operationTimeout)
+}
+
+// startCommand starts the command in its own process group. When the context is done, the whole group, including
+// the processes the command launched, gets SIGTERM and then SIGKILL after the grace period. The returned function
+// waits for the command and returns the context error if the command was stopped.
+func startCommand(ctx context.Context, cmd *exec.Cmd) (func() error, error) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+		// The negative pid stands for the process group.
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+		select {
+		case <-done:
+		case <-time.After(killGracePeriod):
+			_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+	}()
+	return func() error {
+		err := cmd.Wait()
+		close(done)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%s was stopped. Error: %w", commandName(cmd), ctxErr)
+		}
+		return err
+	}, nil
+}
+
+// commandName returns the name of the launched tool. Shell commands are named after the tool the shell launches.
+func commandName(cmd *exec.Cmd) string {
+	name := path.Base(cmd.Path)
+	if name == "sh" && len(cmd.Args) > 2 {
+		if fields := strings.Fields(cmd.Args[2]); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return name
+}
+
+// combinedOutput runs the command like exec.Cmd.CombinedOutput and stops it when the context is done.
+func combinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	wait, err := startCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	err = wait()
+	return out.Bytes(), err
+}
+
+// output runs the command like exec.Cmd.Output and stops it when the context is done.
+func output(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	wait, err := startCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err = wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitErr.Stderr = stderr.Bytes()
+		}
+	}
+	return out.Bytes(), err
+}
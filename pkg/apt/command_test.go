@@ -0,0 +1,33 @@
+package apt
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func Test_combinedOutput(t *testing.T) {
+	msg, err := combinedOutput(context.Background(), exec.Command("sh", "-c", "echo out; echo err >&2"))
+	if err != nil {
+		t.Fatalf("combinedOutput() error = %v", err)
+	}
+	if string(msg) != "out\nerr\n" {
+		t.Errorf("combinedOutput() = %q, want %q", msg, "out\nerr\n")
+	}
+}
+
+func Test_combinedOutput_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	// The background sleep holds the output open, so the command returns only if the whole group is killed.
+	_, err := combinedOutput(ctx, exec.Command("sh", "-c", "sleep 10 & sleep 10"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("combinedOutput() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(started); elapsed > killGracePeriod {
+		t.Errorf("combinedOutput() returned in %v, want the command stopped right away", elapsed)
+	}
+}
@@ -0,0 +1,122 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+var controlFields = []string{
+	"Package", "Version", "Architecture", "Maintainer", "Source", "Pre-Depends", "Depends", "Conflicts", "Breaks",
+	"Replaces", "Provides",
+}
+
+func (m *Manager) ReadControl(ctx context.Context, p *bundle.Package) (*bundle.Control, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	tmpDir, err := os.MkdirTemp(m.tmpDir, fmt.Sprintf("ReadControl-%s-%s-*", p.Name, p.Version))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temporary directory for extracting package %s. Error: %w",
+			p.Path, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer os.RemoveAll(tmpDir)
+	if err = extractPackageFile(p, tmpDir); err != nil {
+		return nil, err
+	}
+	args := append([]string{"-f", path.Join(tmpDir, path.Base(p.Path))}, controlFields...)
+	msg, err := output(ctx, exec.Command("dpkg-deb", args...))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read control fields of %s with dpkg-deb. Error: %w", p.Path, err)
+	}
+	return parseControl(string(msg)), nil
+}
+
+func parseControl(msg string) *bundle.Control {
+	fields := parseControlFields(msg)
+	c := &bundle.Control{
+		Name:         fields["Package"],
+		Version:      fields["Version"],
+		Architecture: fields["Architecture"],
+		Maintainer:   fields["Maintainer"],
+		Conflicts:    flattenRelations(parseRelations(fields["Conflicts"])),
+		Breaks:       flattenRelations(parseRelations(fields["Breaks"])),
+		Replaces:     flattenRelations(parseRelations(fields["Replaces"])),
+		Provides:     flattenRelations(parseRelations(fields["Provides"])),
+	}
+	c.Depends = append(parseRelations(fields["Pre-Depends"]), parseRelations(fields["Depends"])...)
+	// Source is omitted when the source package has the same name, and its version when the versions are equal.
+	c.Source, c.SourceVersion = c.Name, c.Version
+	if source, ok := parseRelation(fields["Source"]); ok {
+		c.Source = source.Name
+		if source.Version != "" {
+			c.SourceVersion = source.Version
+		}
+	}
+	return c
+}
+
+// parseRelations parses a relationship field, for example "libc6 (>= 2.14), debconf | debconf-2.0".
+// Every item of the result lists alternatives.
+func parseRelations(field string) [][]bundle.Relation {
+	relations := make([][]bundle.Relation, 0)
+	for _, item := range strings.Split(field, ",") {
+		alternatives := make([]bundle.Relation, 0, 1)
+		for _, a := range strings.Split(item, "|") {
+			if r, ok := parseRelation(a); ok {
+				alternatives = append(alternatives, r)
+			}
+		}
+		if len(alternatives) > 0 {
+			relations = append(relations, alternatives)
+		}
+	}
+	return relations
+}
+
+// parseRelation parses a single relation, for example "libc6:any (>= 2.14) [amd64]".
+func parseRelation(s string) (bundle.Relation, bool) {
+	s = strings.TrimSpace(s)
+	// Drop architecture restrictions and build profiles
+	if i := strings.IndexAny(s, "[<"); i >= 0 && !strings.Contains(s[:i], "(") {
+		s = strings.TrimSpace(s[:i])
+	}
+	if s == "" {
+		return bundle.Relation{}, false
+	}
+	r := bundle.Relation{}
+	name := s
+	if i := strings.Index(s, "("); i >= 0 {
+		name = strings.TrimSpace(s[:i])
+		constraint := strings.TrimSpace(strings.Trim(s[i:], "()"))
+		if j := strings.Index(constraint, ")"); j >= 0 {
+			constraint = constraint[:j]
+		}
+		r.Operator, r.Version = splitConstraint(constraint)
+	}
+	r.Name = strings.Split(name, ":")[0]
+	return r, true
+}
+
+func splitConstraint(constraint string) (string, string) {
+	i := strings.IndexFunc(constraint, func(c rune) bool {
+		return !strings.ContainsRune("<>=", c)
+	})
+	if i < 0 {
+		return constraint, ""
+	}
+	return constraint[:i], strings.TrimSpace(constraint[i:])
+}
+
+func flattenRelations(relations [][]bundle.Relation) []bundle.Relation {
+	flat := make([]bundle.Relation, 0, len(relations))
+	for _, alternatives := range relations {
+		flat = append(flat, alternatives...)
+	}
+	return flat
+}
@@ -0,0 +1,231 @@
+package apt
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+const (
+	arMagic      = "!<arch>\n"
+	arHeaderSize = 60
+)
+
+// VerifyPackage checks the ar structure of the package file, decompresses its control and data archives and
+// compares the data files with the md5sums of the control archive.
+func (m *Manager) VerifyPackage(ctx context.Context, p *bundle.Package) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	tmpDir, err := os.MkdirTemp(m.tmpDir, fmt.Sprintf("VerifyPackage-%s-%s-*", p.Name, p.Version))
+	if err != nil {
+		return fmt.Errorf("cannot create temporary directory for extracting package %s. Error: %w", p.Path, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer os.RemoveAll(tmpDir)
+	if err = extractPackageFile(p, tmpDir); err != nil {
+		return err
+	}
+	debPath := path.Join(tmpDir, path.Base(p.Path))
+	f, err := os.Open(debPath)
+	if err != nil {
+		return fmt.Errorf("cannot open package %s. Error: %w", debPath, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	if err = verifyArchive(f); err != nil {
+		return fmt.Errorf("%w: %v", bundle.ErrCorruptPackage, err)
+	}
+	var md5sums string
+	err = readDebTar(ctx, debPath, "--ctrl-tarfile", func(h *tar.Header, r io.Reader) (bool, error) {
+		if path.Clean(h.Name) != "md5sums" {
+			return false, nil
+		}
+		data, err := io.ReadAll(r)
+		md5sums = string(data)
+		return false, err
+	})
+	// A stopped dpkg-deb doesn't mean that the package file is corrupt.
+	if ctx.Err() != nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: cannot read the control archive: %v", bundle.ErrCorruptPackage, err)
+	}
+	sums, err := parseMD5Sums(md5sums)
+	if err != nil {
+		return fmt.Errorf("%w: %v", bundle.ErrCorruptPackage, err)
+	}
+	// hashes are the md5sums of the regular files by name. A hardlink goes after its target in the archive and
+	// has the md5sum of the target.
+	hashes := make(map[string]string)
+	err = readDebTar(ctx, debPath, "--fsys-tarfile", func(h *tar.Header, r io.Reader) (bool, error) {
+		name := path.Clean(h.Name)
+		var got string
+		switch h.Typeflag {
+		case tar.TypeReg:
+			hash := md5.New()
+			if _, err := io.Copy(hash, r); err != nil {
+				return false, err
+			}
+			got = hex.EncodeToString(hash.Sum(nil))
+			hashes[name] = got
+		case tar.TypeLink:
+			var ok bool
+			if got, ok = hashes[path.Clean(h.Linkname)]; !ok {
+				return false, fmt.Errorf("hardlink %s points to %s, which is not a preceding regular file",
+					name, h.Linkname)
+			}
+		default:
+			return false, nil
+		}
+		want, ok := sums[name]
+		if !ok {
+			return false, nil
+		}
+		delete(sums, name)
+		if got != want {
+			return false, fmt.Errorf("md5sum of %s is %s, but %s expected", name, got, want)
+		}
+		return false, nil
+	})
+	if ctx.Err() != nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: cannot verify the data archive: %v", bundle.ErrCorruptPackage, err)
+	}
+	if len(sums) > 0 {
+		missing := make([]string, 0, len(sums))
+		for name := range sums {
+			missing = append(missing, name)
+		}
+		sort.Strings(missing)
+		return fmt.Errorf("%w: files listed in md5sums are missing in the data archive: %s",
+			bundle.ErrCorruptPackage, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// verifyArchive checks that the file is an ar archive with the debian-binary, control and data members
+// in this order and that no member is truncated.
+func verifyArchive(r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != arMagic {
+		return fmt.Errorf("the file is not an ar archive")
+	}
+	var members []string
+	header := make([]byte, arHeaderSize)
+	for {
+		if _, err := io.ReadFull(br, header); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("ar member header #%d is truncated", len(members)+1)
+		}
+		if string(header[58:60]) != "`\n" {
+			return fmt.Errorf("ar member header #%d is malformed", len(members)+1)
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(string(header[0:16])), "/")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(header[48:58])), 10, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("ar member %s has invalid size", name)
+		}
+		var content bytes.Buffer
+		if len(members) == 0 {
+			_, err = io.CopyN(&content, br, size)
+		} else {
+			_, err = io.CopyN(io.Discard, br, size)
+		}
+		if err != nil {
+			return fmt.Errorf("ar member %s is truncated: %d bytes expected", name, size)
+		}
+		if len(members) == 0 && (name != "debian-binary" || !strings.HasPrefix(content.String(), "2.")) {
+			return fmt.Errorf("the first ar member is %s, but debian-binary of format 2.x expected", name)
+		}
+		// Members are aligned to an even offset.
+		if size%2 == 1 {
+			if _, err = br.Discard(1); err != nil && err != io.EOF {
+				return err
+			}
+		}
+		members = append(members, name)
+	}
+	if len(members) < 3 || !strings.HasPrefix(members[1], "control.tar") ||
+		!strings.HasPrefix(members[2], "data.tar") {
+		return fmt.Errorf("ar members are %s, but debian-binary, control.tar and data.tar expected",
+			strings.Join(members, ", "))
+	}
+	return nil
+}
+
+// parseMD5Sums parses the md5sums control file with "<md5sum>  <path>" lines. Paths are relative to the root.
+func parseMD5Sums(md5sums string) (map[string]string, error) {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(md5sums, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || len(parts[0]) != md5.Size*2 {
+			return nil, fmt.Errorf("cannot parse md5sums line \"%s\"", line)
+		}
+		sums[path.Clean(strings.TrimLeft(parts[1], " *"))] = parts[0]
+	}
+	return sums, nil
+}
+
+// readDebTar reads the control or data archive of the package file with dpkg-deb, which decompresses it, and
+// calls the function for every entry until it returns true. The archive is read to the end anyway, so that
+// decompression errors are found.
+func readDebTar(ctx context.Context, debPath, option string, fn func(h *tar.Header, r io.Reader) (bool, error)) error {
+	cmd := exec.Command("dpkg-deb", option, debPath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("cannot read %s with dpkg-deb. Error: %w", debPath, err)
+	}
+	wait, err := startCommand(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("cannot launch dpkg-deb command. Error: %w", err)
+	}
+	readErr := readTar(out, fn)
+	_, _ = io.Copy(io.Discard, out)
+	if err = wait(); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("dpkg-deb %s failed: %s", option, strings.TrimSpace(stderr.String()))
+	}
+	return readErr
+}
+
+func readTar(r io.Reader, fn func(h *tar.Header, r io.Reader) (bool, error)) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		done, err := fn(h, tr)
+		if err != nil || done {
+			return err
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package apt
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+// arArchive builds an ar archive of the named members.
+func arArchive(members ...[2]string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(arMagic)
+	for _, m := range members {
+		fmt.Fprintf(&buf, "%-16s%-12s%-6s%-6s%-8s%-10d`\n", m[0], "0", "0", "0", "100644", len(m[1]))
+		buf.WriteString(m[1])
+		if len(m[1])%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func Test_verifyArchive(t *testing.T) {
+	valid := arArchive([2]string{"debian-binary", "2.0\n"}, [2]string{"control.tar.xz", "control"},
+		[2]string{"data.tar.xz", "data"})
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr string
+	}{
+		{name: "valid", archive: valid},
+		{name: "truncated", archive: valid[:len(valid)-3], wantErr: "ar member data.tar.xz is truncated"},
+		{name: "not ar", archive: []byte("PK\x03\x04"), wantErr: "the file is not an ar archive"},
+		{
+			name: "no data",
+			archive: arArchive([2]string{"debian-binary", "2.0\n"},
+				[2]string{"control.tar.gz", "control"}),
+			wantErr: "ar members are debian-binary, control.tar.gz",
+		},
+		{
+			name:    "unknown format",
+			archive: arArchive([2]string{"debian-binary", "3.0\n"}),
+			wantErr: "debian-binary of format 2.x expected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyArchive(bytes.NewReader(tt.archive))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("verifyArchive() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("verifyArchive() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_parseMD5Sums(t *testing.T) {
+	got, err := parseMD5Sums("0123456789abcdef0123456789abcdef  usr/sbin/chronyd\n" +
+		"fedcba9876543210fedcba9876543210  ./usr/share/doc/chrony/copyright\n")
+	if err != nil {
+		t.Fatalf("parseMD5Sums() error = %v", err)
+	}
+	want := map[string]string{
+		"usr/sbin/chronyd":               "0123456789abcdef0123456789abcdef",
+		"usr/share/doc/chrony/copyright": "fedcba9876543210fedcba9876543210",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMD5Sums() = %v, want %v", got, want)
+	}
+	if _, err = parseMD5Sums("broken line"); err == nil {
+		t.Errorf("parseMD5Sums() error = nil, want an error")
+	}
+}
+
+func TestManager_VerifyPackage_Hardlink(t *testing.T) {
+	if _, err := exec.LookPath("dpkg-deb"); err != nil {
+		t.Skip("dpkg-deb is not installed")
+	}
+	sum := fmt.Sprintf("%x", md5.Sum([]byte("#!/bin/sh\n")))
+	tests := []struct {
+		name    string
+		md5sums string
+		wantErr bool
+	}{
+		{
+			name:    "verifies hardlink against its target",
+			md5sums: sum + "  usr/bin/chronyc\n" + sum + "  usr/bin/chronyd\n",
+		},
+		{
+			name:    "finds hardlink with wrong md5sum",
+			md5sums: sum + "  usr/bin/chronyc\n" + strings.Repeat("0", 32) + "  usr/bin/chronyd\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := path.Join(dir, "root")
+			for _, d := range []string{"DEBIAN", "usr/bin"} {
+				if err := os.MkdirAll(path.Join(root, d), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			files := map[string]string{
+				"DEBIAN/control": "Package: chrony\nVersion: 3.2\nArchitecture: all\nMaintainer: Test <test@example.com>\n" +
+					"Description: test\n",
+				"DEBIAN/md5sums":  tt.md5sums,
+				"usr/bin/chronyc": "#!/bin/sh\n",
+			}
+			for name, data := range files {
+				if err := os.WriteFile(path.Join(root, name), []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.Link(path.Join(root, "usr/bin/chronyc"), path.Join(root, "usr/bin/chronyd")); err != nil {
+				t.Fatal(err)
+			}
+			if msg, err := exec.Command("dpkg-deb", "--build", root, path.Join(dir, "chrony_3.2_all.deb")).
+				CombinedOutput(); err != nil {
+				t.Fatalf("dpkg-deb --build error = %v: %s", err, msg)
+			}
+			m := &Manager{tmpDir: dir}
+			p, err := bundle.NewPackage(os.DirFS(dir), "chrony_3.2_all.deb", m)
+			if err != nil {
+				t.Fatalf("NewPackage() error = %v", err)
+			}
+			err = m.VerifyPackage(context.Background(), p)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, bundle.ErrCorruptPackage)) {
+				t.Errorf("VerifyPackage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
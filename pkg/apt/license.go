@@ -0,0 +1,218 @@
+package apt
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+// spdxLicenses maps the short license names of Debian copyright files to SPDX license identifiers.
+var spdxLicenses = map[string]string{
+	"apache-2.0":    "Apache-2.0",
+	"artistic":      "Artistic-1.0-Perl",
+	"bsd-2-clause":  "BSD-2-Clause",
+	"bsd-3-clause":  "BSD-3-Clause",
+	"bsd-4-clause":  "BSD-4-Clause",
+	"bsl-1.0":       "BSL-1.0",
+	"cc0-1.0":       "CC0-1.0",
+	"expat":         "MIT",
+	"gfdl-1.2":      "GFDL-1.2-only",
+	"gfdl-1.3":      "GFDL-1.3-only",
+	"gpl":           "GPL-1.0-or-later",
+	"gpl-1+":        "GPL-1.0-or-later",
+	"gpl-2":         "GPL-2.0-only",
+	"gpl-2+":        "GPL-2.0-or-later",
+	"gpl-3":         "GPL-3.0-only",
+	"gpl-3+":        "GPL-3.0-or-later",
+	"isc":           "ISC",
+	"lgpl-2":        "LGPL-2.0-only",
+	"lgpl-2+":       "LGPL-2.0-or-later",
+	"lgpl-2.1":      "LGPL-2.1-only",
+	"lgpl-2.1+":     "LGPL-2.1-or-later",
+	"lgpl-3":        "LGPL-3.0-only",
+	"lgpl-3+":       "LGPL-3.0-or-later",
+	"mit":           "MIT",
+	"mpl-1.1":       "MPL-1.1",
+	"mpl-2.0":       "MPL-2.0",
+	"openssl":       "OpenSSL",
+	"public-domain": "LicenseRef-public-domain",
+	"python-2.0":    "Python-2.0",
+	"zlib":          "Zlib",
+}
+
+// spdxExceptions maps the names of Debian license exceptions without the "exception" word to SPDX license
+// exception identifiers.
+var spdxExceptions = map[string]string{
+	"autoconf":  "Autoconf-exception-generic",
+	"bison":     "Bison-exception-2.2",
+	"classpath": "Classpath-exception-2.0",
+	"font":      "Font-exception-2.0",
+	"libtool":   "Libtool-exception",
+}
+
+var (
+	paragraphSeparatorReg = regexp.MustCompile(`\n[ \t]*\n`)
+	filesFieldReg         = regexp.MustCompile(`(?m)^Files:`)
+	licenseFieldReg       = regexp.MustCompile(`(?m)^License:[ \t]*(.+)$`)
+	commonLicensesReg     = regexp.MustCompile(`/usr/share/common-licenses/([A-Za-z0-9.+-]+)`)
+	licenseGroupReg       = regexp.MustCompile(`\s*,\s*`)
+	licenseOperatorReg    = regexp.MustCompile(`(?i)\s+(or|and)\s+`)
+	licenseExceptionReg   = regexp.MustCompile(`(?i)\s+with\s+`)
+	licenseRefReg         = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+)
+
+// ReadLicenses reads the copyright file of the package from its data archive and returns the licenses of it.
+func (m *Manager) ReadLicenses(ctx context.Context, p *bundle.Package) ([]string, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	tmpDir, err := os.MkdirTemp(m.tmpDir, fmt.Sprintf("ReadLicenses-%s-%s-*", p.Name, p.Version))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temporary directory for extracting package %s. Error: %w",
+			p.Path, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer os.RemoveAll(tmpDir)
+	if err = extractPackageFile(p, tmpDir); err != nil {
+		return nil, err
+	}
+	var copyright string
+	docPath := path.Join("usr/share/doc", p.Name, "copyright")
+	err = readDebTar(ctx, path.Join(tmpDir, path.Base(p.Path)), "--fsys-tarfile",
+		func(h *tar.Header, r io.Reader) (bool, error) {
+			// Packages that share the documentation directory of another package have no copyright file.
+			if path.Clean(h.Name) != docPath || h.Typeflag != tar.TypeReg {
+				return false, nil
+			}
+			data, err := io.ReadAll(r)
+			copyright = string(data)
+			return true, err
+		})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read copyright file of %s. Error: %w", p.Path, err)
+	}
+	return parseCopyright(copyright), nil
+}
+
+// parseCopyright returns the SPDX license expressions from the License fields of a machine-readable copyright
+// file or the SPDX license identifiers from the references to /usr/share/common-licenses of a free-form one.
+// Unknown licenses and exceptions become LicenseRef- identifiers.
+func parseCopyright(copyright string) []string {
+	expressions := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(expression string) {
+		if expression != "" && !seen[expression] {
+			seen[expression] = true
+			expressions = append(expressions, expression)
+		}
+	}
+	for _, field := range licenseFields(copyright) {
+		add(licenseExpression(field))
+	}
+	if len(expressions) == 0 {
+		for _, m := range commonLicensesReg.FindAllStringSubmatch(copyright, -1) {
+			add(spdxLicense(m[1]))
+		}
+	}
+	return expressions
+}
+
+// licenseFields returns the License fields of the Files paragraphs. The stand-alone License paragraphs only
+// hold the license texts, so their fields are used only when there are no Files paragraphs.
+func licenseFields(copyright string) []string {
+	files, all := make([]string, 0), make([]string, 0)
+	for _, paragraph := range paragraphSeparatorReg.Split(copyright, -1) {
+		m := licenseFieldReg.FindStringSubmatch(paragraph)
+		if m == nil {
+			continue
+		}
+		all = append(all, m[1])
+		if filesFieldReg.MatchString(paragraph) {
+			files = append(files, m[1])
+		}
+	}
+	if len(files) > 0 {
+		return files
+	}
+	return all
+}
+
+// licenseExpression converts the Debian license field, for example "GPL-2+ or Artistic, and BSD-3-clause"
+// or "GPL-2+ with OpenSSL exception", to an SPDX license expression. Both give "and" a higher precedence than
+// "or", and the comma separated groups are combined with the operator that starts them or with AND.
+func licenseExpression(field string) string {
+	groups := licenseGroupReg.Split(strings.TrimSpace(field), -1)
+	var expression strings.Builder
+	for _, group := range groups {
+		operator := "AND"
+		if lower := strings.ToLower(group); strings.HasPrefix(lower, "or ") || strings.HasPrefix(lower, "and ") {
+			operator = strings.ToUpper(group[:strings.Index(group, " ")])
+			group = strings.TrimSpace(group[len(operator):])
+		}
+		terms := licenseOperatorReg.Split(group, -1)
+		operators := licenseOperatorReg.FindAllStringSubmatch(group, -1)
+		var groupExpression strings.Builder
+		for i, term := range terms {
+			license := licenseTerm(term)
+			if license == "" {
+				continue
+			}
+			if groupExpression.Len() > 0 {
+				groupExpression.WriteString(" " + strings.ToUpper(operators[i-1][1]) + " ")
+			}
+			groupExpression.WriteString(license)
+		}
+		if groupExpression.Len() == 0 {
+			continue
+		}
+		s := groupExpression.String()
+		if len(groups) > 1 && (strings.Contains(s, " AND ") || strings.Contains(s, " OR ")) {
+			s = "(" + s + ")"
+		}
+		if expression.Len() > 0 {
+			expression.WriteString(" " + operator + " ")
+		}
+		expression.WriteString(s)
+	}
+	return expression.String()
+}
+
+// licenseTerm converts a license with an optional exception, for example "GPL-2+ with OpenSSL exception".
+func licenseTerm(term string) string {
+	parts := licenseExceptionReg.Split(term, 2)
+	license := spdxLicense(parts[0])
+	if license == "" || len(parts) == 1 {
+		return license
+	}
+	name := strings.Trim(strings.TrimSpace(parts[1]), ".")
+	if lower := strings.ToLower(name); strings.HasSuffix(lower, " exception") {
+		name = strings.TrimSpace(name[:len(name)-len(" exception")])
+	}
+	if name == "" {
+		return license
+	}
+	exception, ok := spdxExceptions[strings.ToLower(name)]
+	if !ok {
+		exception = "LicenseRef-" + strings.Trim(licenseRefReg.ReplaceAllString(name, "-"), "-") + "-exception"
+	}
+	return license + " WITH " + exception
+}
+
+// spdxLicense returns the SPDX license identifier of the Debian license name. It returns an empty string for
+// an empty name.
+func spdxLicense(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if name == "" {
+		return ""
+	}
+	if license, ok := spdxLicenses[strings.ToLower(name)]; ok {
+		return license
+	}
+	return "LicenseRef-" + strings.Trim(licenseRefReg.ReplaceAllString(name, "-"), "-")
+}
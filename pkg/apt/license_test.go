@@ -0,0 +1,70 @@
+package apt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseCopyright(t *testing.T) {
+	tests := []struct {
+		name      string
+		copyright string
+		want      []string
+	}{
+		{
+			name: "machine-readable",
+			copyright: `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: chrony
+
+Files: *
+Copyright: 1997-2007 Richard P. Curnow
+License: GPL-2
+
+Files: debian/*
+Copyright: 2009-2018 Vincent Blut
+License: GPL-2+ or BSD-3-clause
+
+Files: getdate.c
+License: public-domain
+
+License: GPL-2
+ On Debian systems, the full text of the GNU General Public License version 2
+ can be found in the file /usr/share/common-licenses/GPL-2.`,
+			want: []string{"GPL-2.0-only", "GPL-2.0-or-later OR BSD-3-Clause", "LicenseRef-public-domain"},
+		},
+		{
+			name: "free-form",
+			copyright: `This package was debianized by Ubuntu Developers.
+
+On Debian systems, the complete text of the GNU Lesser General Public License can be found in
+/usr/share/common-licenses/LGPL-2.1.`,
+			want: []string{"LGPL-2.1-only"},
+		},
+		{
+			name:      "unknown",
+			copyright: "License: Custom license with OpenSSL exception and MIT",
+			want:      []string{"LicenseRef-Custom-license WITH LicenseRef-OpenSSL-exception AND MIT"},
+		},
+		{
+			name:      "known exception",
+			copyright: "Files: *\nLicense: GPL-2+ with Autoconf exception",
+			want:      []string{"GPL-2.0-or-later WITH Autoconf-exception-generic"},
+		},
+		{
+			name:      "comma separated groups",
+			copyright: "Files: *\nLicense: GPL-2+ or Artistic, and BSD-3-clause",
+			want:      []string{"(GPL-2.0-or-later OR Artistic-1.0-Perl) AND BSD-3-Clause"},
+		},
+		{
+			name: "no licenses",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCopyright(tt.copyright); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCopyright() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package apt
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
@@ -8,7 +9,9 @@ import (
 	"os/exec"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"konvoy-os-package-builder/bundle"
 )
@@ -18,16 +21,67 @@ const aptCachePath = "/var/cache/apt/archives/"
 var _ bundle.PackageManager = &Manager{}
 
 type Manager struct {
-	tmpDir string
+	tmpDir       string
+	architecture string
+	// root is the isolated APT root directory. Empty root means the host APT configuration.
+	root string
+	// operationTimeout limits every operation of the manager. Zero means no limit.
+	operationTimeout time.Duration
+}
+
+// Config configures the APT package manager.
+type Config struct {
+	// Architecture is the architecture the manager simulates installation and downloads packages for.
+	// Empty architecture means the native architecture of the host.
+	Architecture string
+	// Sources are APT source lines, for example "deb http://archive.ubuntu.com/ubuntu bionic main".
+	// When sources are set, the manager resolves packages in an isolated root with these sources only.
+	Sources []string
+	// Keyrings are paths to the keyring files that sign the sources.
+	Keyrings []string
+	// Isolated makes the manager resolve packages in an isolated root with nothing installed,
+	// as on a clean node, even for the native architecture and the host sources.
+	Isolated bool
+	// Offline makes the manager resolve packages in an isolated root without any package sources, so that only
+	// the checked package files and the installed packages satisfy the dependencies. It overrides the sources.
+	Offline bool
+	// Status is the dpkg status of the packages installed in the isolated root, for example the status file of
+	// the base image. Empty means nothing is installed.
+	Status string
+	// OperationTimeout limits every operation of the manager, for example a simulated installation or a download,
+	// so that a held lock or a slow mirror doesn't block the run forever. Zero means no limit.
+	OperationTimeout time.Duration
 }
 
 func NewManager() (*Manager, error) {
-	m := &Manager{}
+	return NewManagerWithConfig(context.Background(), Config{})
+}
+
+// NewManagerWithConfig creates the manager. The context stops the download of the package lists into
+// the isolated root.
+func NewManagerWithConfig(ctx context.Context, cfg Config) (*Manager, error) {
+	m := &Manager{operationTimeout: cfg.OperationTimeout}
 	var err error
 	m.tmpDir, err = os.MkdirTemp("", "konvoy-os-package-builder-*")
 	if err != nil {
 		return nil, fmt.Errorf("cannot create a temporary directory for APT package manager. Error: %w", err)
 	}
+	m.architecture, err = nativeArchitecture()
+	if err != nil {
+		return nil, err
+	}
+	foreign := cfg.Architecture != "" && cfg.Architecture != m.architecture
+	if !foreign && len(cfg.Sources) == 0 && !cfg.Isolated && !cfg.Offline {
+		return m, nil
+	}
+	// Foreign architecture packages and packages from other sources are resolved in an isolated root,
+	// so the host packages and sources do not interfere.
+	if foreign {
+		m.architecture = cfg.Architecture
+	}
+	if err = m.setupRoot(ctx, cfg); err != nil {
+		return nil, err
+	}
 	return m, nil
 }
 
@@ -35,6 +89,11 @@ func (m *Manager) Name() string {
 	return "apt"
 }
 
+// Architecture returns the architecture the manager works with.
+func (m *Manager) Architecture() string {
+	return m.architecture
+}
+
 func (m *Manager) ParseNameVersion(packageFileName string) (bundle.NameVersion, error) {
 	// Fix package directory name
 	packageFileName = strings.Replace(packageFileName, "=", "_", -1)
@@ -45,54 +104,78 @@ func (m *Manager) ParseNameVersion(packageFileName string) (bundle.NameVersion,
 	return bundle.NameVersion{Name: parts[0], Version: parts[1]}, nil
 }
 
+func (m *Manager) ParseArchitecture(packageFileName string) string {
+	parts := strings.Split(strings.TrimSuffix(packageFileName, ".deb"), "_")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
+
 func (m *Manager) IsMain(packageDirName, packageFileName string) bool {
-	// Fix package directory name
+	// Fix package directory name. The name and the version are followed by "_", so that docker-ce
+	// doesn't match docker-ce-cli_20.10.7_amd64.deb.
 	packageDirName = strings.Replace(packageDirName, "=", "_", -1)
-	return strings.HasPrefix(packageFileName, packageDirName)
+	return strings.HasPrefix(packageFileName, packageDirName+"_")
 }
 
-func (m *Manager) CheckInstall(p *bundle.Package) (bundle.InstallResult, error) {
-	res := bundle.InstallResult{Package: p}
-	packageTmpDir, err := os.MkdirTemp(m.tmpDir, fmt.Sprintf("CheckInstall-%s-%s-*", p.Name, p.Version))
-	if err != nil {
-		return res, fmt.Errorf("cannot create temporary directory for to install package %s. Error: %w",
-			p.Path, err)
-	}
-	if err = extractPackage(p, packageTmpDir); err != nil {
-		return res, fmt.Errorf("cannot copy package %s to %s. Error: %w", p.Path, packageTmpDir, err)
-	}
-	cmd := exec.Command("sh", "-c", "apt-get install -s -y "+path.Join(packageTmpDir, "*"))
-	msg, err := cmd.CombinedOutput()
-	if err == nil {
-		res.Result = bundle.ResultOk
-		return res, nil
+func (m *Manager) CheckInstall(ctx context.Context, p *bundle.Package) (bundle.InstallResult, error) {
+	res, err := m.CheckInstallAll(ctx, []*bundle.Package{p})
+	res.Package = p
+	return res, err
+}
+
+func (m *Manager) CheckInstallAll(ctx context.Context, pp []*bundle.Package) (bundle.InstallResult, error) {
+	res := bundle.InstallResult{}
+	pattern := "CheckInstallAll-*"
+	if len(pp) == 1 {
+		pattern = fmt.Sprintf("CheckInstall-%s-%s-*", pp[0].Name, pp[0].Version)
 	}
-	if _, ok := err.(*exec.ExitError); !ok {
-		res.Result = bundle.ResultUnknownProblem
-		return res, fmt.Errorf("cannot launch apt-get command. Error: %w", err)
+	packageTmpDir, err := os.MkdirTemp(m.tmpDir, pattern)
+	if err != nil {
+		return res, fmt.Errorf("cannot create temporary directory for to install packages. Error: %w", err)
 	}
-	res.Result = parseResultType(string(msg))
-	if res.Result != bundle.ResultUnmetDependencies {
-		return res, nil
+	for _, p := range pp {
+		if err = extractPackage(p, packageTmpDir); err != nil {
+			return res, fmt.Errorf("cannot copy package %s to %s. Error: %w", p.Path, packageTmpDir, err)
+		}
 	}
-	res.UnmetDependencies = parseDependencies(string(msg))
-	return res, nil
+	err = m.simulateInstall(ctx, &res, path.Join(packageTmpDir, "*"))
+	return res, err
 }
 
-func (m *Manager) CheckInstallLatestVersion(name string) (bundle.InstallResultType, error) {
-	cmd := exec.Command("sh", "-c", "apt-get -s install -y "+name)
-	msg, err := cmd.CombinedOutput()
-	if err == nil {
-		return bundle.ResultOk, nil
-	}
-	if _, ok := err.(*exec.ExitError); !ok {
-		return -1, fmt.Errorf("cannot launch apt-get command. Error: %w", err)
+func (m *Manager) CheckInstallSet(ctx context.Context, set []bundle.NameVersion) (bundle.InstallResult, error) {
+	res := bundle.InstallResult{}
+	err := m.simulateInstall(ctx, &res, strings.Join(targets(set), " "))
+	return res, err
+}
+
+func (m *Manager) ListVersions(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	msg, err := combinedOutput(ctx, m.aptCommand("apt-cache", "madison "+name))
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, fmt.Errorf("cannot run apt-cache command. Error: %w", err)
+		}
+		return nil, fmt.Errorf("cannot list versions of package %s with apt-cache madison. Command ouput:\n%s",
+			name, string(msg))
 	}
-	return parseResultType(string(msg)), nil
+	versions := parseMadison(string(msg))
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) > 0
+	})
+	return versions, nil
 }
 
-func (m *Manager) UpdateDependencies(p *bundle.Package) error {
-	if err := clearAPTCache(); err != nil {
+func (m *Manager) CompareVersions(a, b string) int {
+	return compareVersions(a, b)
+}
+
+func (m *Manager) UpdateDependencies(ctx context.Context, p *bundle.Package) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	if err := m.clearCache(); err != nil {
 		return err
 	}
 	tmpDir, err := os.MkdirTemp(m.tmpDir, fmt.Sprintf("UpdateDependencies-%s-%s-*", p.Name, p.Version))
@@ -103,11 +186,12 @@ func (m *Manager) UpdateDependencies(p *bundle.Package) error {
 	if err = extractPackage(p, tmpDir); err != nil {
 		return fmt.Errorf("cannot extraact package %s to %s. Error: %w", p.Path, tmpDir, err)
 	}
-	cmd := exec.Command("sh", "-c", "apt-get install -d -y --reinstall "+path.Join(tmpDir, "*"))
-	msg, err := cmd.CombinedOutput()
+	stopWatch := m.watchDownload(ctx)
+	msg, err := combinedOutput(ctx, m.aptCommand("apt-get", "install -d -y --reinstall "+path.Join(tmpDir, "*")))
+	stopWatch()
 	if err != nil {
 		if _, ok := err.(*exec.ExitError); !ok {
-			return fmt.Errorf("cannot launch apt-get command. Error: %w", err)
+			return fmt.Errorf("cannot run apt-get command. Error: %w", err)
 		}
 		return fmt.Errorf("cannot download package %s with apt-get install -d. Command ouput:\n%s",
 			p.Path, string(msg))
@@ -118,7 +202,7 @@ func (m *Manager) UpdateDependencies(p *bundle.Package) error {
 	if err = os.Mkdir(downloadedDependenciesDir, 0700); err != nil {
 		return fmt.Errorf("cannot create directory for downloaded dependencies. Error: %w", err)
 	}
-	if err := copyDebFilesFromAptCache(downloadedDependenciesDir); err != nil {
+	if err := m.copyDebFilesFromCache(ctx, downloadedDependenciesDir); err != nil {
 		return err
 	}
 	fileSystem := os.DirFS(downloadedDependenciesDir)
@@ -131,34 +215,86 @@ func (m *Manager) UpdateDependencies(p *bundle.Package) error {
 		if err != nil {
 			return fmt.Errorf("cannot create downloaded package %s. Error: %w", f.Name(), err)
 		}
+		dep.Origin = bundle.OriginDownloaded
 		p.Dependencies = append(p.Dependencies, dep)
 	}
 	return nil
 }
 
-func (m *Manager) DownloadLatestVersion(name string) (*bundle.Package, error) {
-	if err := clearAPTCache(); err != nil {
+func (m *Manager) DownloadLatestVersion(ctx context.Context, name string) (*bundle.Package, error) {
+	return m.download(ctx, name, []string{name}, "DownloadLatestVersion")
+}
+
+func (m *Manager) DownloadVersion(ctx context.Context, name, version string) (*bundle.Package, error) {
+	return m.download(ctx, name, []string{name + "=" + version}, "DownloadVersion")
+}
+
+func (m *Manager) DownloadSet(ctx context.Context, packageDirName string,
+	set []bundle.NameVersion) (*bundle.Package, error) {
+	return m.download(ctx, packageDirName, targets(set), "DownloadSet")
+}
+
+func (m *Manager) Clean() error {
+	if err := os.RemoveAll(m.tmpDir); err != nil {
+		return fmt.Errorf("cannot remove temporary directory %s. Error: %w", m.tmpDir, err)
+	}
+	return nil
+}
+
+// simulateInstall simulates installation of the apt-get install arguments and fills the result.
+func (m *Manager) simulateInstall(ctx context.Context, res *bundle.InstallResult, args string) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	bundle.ProgressFrom(ctx).SetPhase(bundle.PhaseSimulate)
+	msg, err := combinedOutput(ctx, m.aptCommand("apt-get", "install -s -y "+args))
+	res.Output = string(msg)
+	res.Transaction = parseTransaction(res.Output)
+	if err == nil {
+		res.Result = bundle.ResultOk
+		return nil
+	}
+	if _, ok := err.(*exec.ExitError); !ok {
+		res.Result = bundle.ResultUnknownProblem
+		return fmt.Errorf("cannot run apt-get command. Error: %w", err)
+	}
+	res.Result = parseResultType(res.Output)
+	res.Details = parseResultDetails(res.Result, res.Output)
+	if res.Result == bundle.ResultUnmetDependencies {
+		res.UnmetDependencies = parseDependencies(res.Output)
+	}
+	return nil
+}
+
+// download downloads the targets (name or name=version) with their dependencies and creates
+// a package from the downloaded files in the package directory.
+func (m *Manager) download(ctx context.Context, packageDirName string, targets []string,
+	operation string) (*bundle.Package, error) {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	target := strings.Join(targets, " ")
+	if err := m.clearCache(); err != nil {
 		return nil, err
 	}
-	cmd := exec.Command("sh", "-c", "apt-get install -d -y --reinstall "+name)
-	msg, err := cmd.CombinedOutput()
+	stopWatch := m.watchDownload(ctx)
+	msg, err := combinedOutput(ctx, m.aptCommand("apt-get", "install -d -y --reinstall "+target))
+	stopWatch()
 	if err != nil {
 		if _, ok := err.(*exec.ExitError); !ok {
-			return nil, fmt.Errorf("cannot launch apt-get command. Error: %w", err)
+			return nil, fmt.Errorf("cannot run apt-get command. Error: %w", err)
 		}
 		return nil, fmt.Errorf("cannot download package %s with apt-get install -d. Command ouput:\n%s",
-			name, string(msg))
+			target, string(msg))
 	}
-	tmpDir, err := os.MkdirTemp(m.tmpDir, fmt.Sprintf("DownloadLatestVersion-%s-*", name))
+	tmpDir, err := os.MkdirTemp(m.tmpDir, fmt.Sprintf("%s-%s-*", operation, packageDirName))
 	if err != nil {
 		return nil, fmt.Errorf("cannot create temporary directory for to download package %s. Error: %w",
-			name, err)
+			packageDirName, err)
 	}
-	packageDir := path.Join(tmpDir, name)
+	packageDir := path.Join(tmpDir, packageDirName)
 	if err = os.Mkdir(packageDir, 0700); err != nil {
 		return nil, fmt.Errorf("cannot create package dir %s. Error: %w", packageDir, err)
 	}
-	if err := copyDebFilesFromAptCache(packageDir); err != nil {
+	if err := m.copyDebFilesFromCache(ctx, packageDir); err != nil {
 		return nil, err
 	}
 	fileSystem := os.DirFS(tmpDir)
@@ -166,52 +302,98 @@ func (m *Manager) DownloadLatestVersion(name string) (*bundle.Package, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cannot create downloaded package. Error: %w", err)
 	}
+	newP.SetOrigin(bundle.OriginDownloaded)
 	return newP, nil
 }
 
-func (m *Manager) Clean() error {
-	if err := os.RemoveAll(m.tmpDir); err != nil {
-		return fmt.Errorf("cannot remove temporary directory %s. Error: %w", m.tmpDir, err)
+// parseMadison parses the output of apt-cache madison and returns the unique binary package versions.
+func parseMadison(msg string) []string {
+	versions := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(msg, "\n") {
+		parts := strings.Split(line, "|")
+		if len(parts) < 3 || strings.HasSuffix(strings.TrimSpace(parts[2]), "Sources") {
+			continue
+		}
+		v := strings.TrimSpace(parts[1])
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		versions = append(versions, v)
 	}
-	return nil
+	return versions
 }
 
-func parseResultType(msg string) bundle.InstallResultType {
-	msg = strings.ToLower(msg)
-	if strings.Contains(msg, "will be downgraded") {
-		return bundle.ResultNewerAlreadyInstalled
-	}
-	if strings.Contains(msg, "unmet dependencies") {
-		return bundle.ResultUnmetDependencies
-	}
-	if strings.Contains(msg, "unable to locate package") {
-		return bundle.ResultCannotFindPackage
+func targets(set []bundle.NameVersion) []string {
+	tt := make([]string, len(set))
+	for i, nv := range set {
+		tt[i] = nv.Name
+		if nv.Version != "" {
+			tt[i] += "=" + nv.Version
+		}
 	}
-	return bundle.ResultUnknownProblem
+	return tt
 }
 
-var depReg = regexp.MustCompile(`Depends:\s*(\S+)\s+(?:\(>=\s*(\d+\.\d+\.\d+)\))?`)
+// depReg matches the first line of an unmet dependency, for example
+// " kubeadm : Depends: kubelet (>= 1.13.0) but it is not installable".
+var depReg = regexp.MustCompile(`^\s*(?:\S+\s+:\s+)?(?:Pre-?)?Depends:\s*(.+)$`)
 
-func parseDependencies(msg string) []bundle.NameVersion {
-	dd := depReg.FindAllStringSubmatch(msg, -1)
-	deps := make([]bundle.NameVersion, len(dd))
-	for i, d := range dd {
-		deps[i].Name = d[1]
-		if len(d) > 2 {
-			deps[i].Version = d[2]
+// fieldReg matches the first line of other unmet relations, for example "Breaks: kubelet (< 1.13.0)".
+var fieldReg = regexp.MustCompile(`^\s*(?:\S+\s+:\s+)?[A-Za-z-]+:\s`)
+
+// parseDependencies parses unmet dependencies from the apt-get output. Every item lists alternatives,
+// any of which satisfies the dependency.
+func parseDependencies(msg string) [][]bundle.Relation {
+	deps := make([][]bundle.Relation, 0)
+	continued := false
+	for _, line := range strings.Split(msg, "\n") {
+		var text string
+		if d := depReg.FindStringSubmatch(line); d != nil {
+			text = d[1]
+			deps = append(deps, make([]bundle.Relation, 0, 1))
+		} else if continued && !fieldReg.MatchString(line) {
+			text = strings.TrimSpace(line)
+		} else {
+			continued = false
+			continue
+		}
+		// Alternatives continue on the next line after "or".
+		continued = strings.HasSuffix(text, " or")
+		text = strings.TrimSuffix(text, " or")
+		if i := strings.Index(text, " but "); i >= 0 {
+			text = text[:i]
+		}
+		if r, ok := parseRelation(text); ok {
+			deps[len(deps)-1] = append(deps[len(deps)-1], r)
 		}
 	}
 	return deps
 }
 
-func clearAPTCache() error {
-	if err := os.RemoveAll(aptCachePath); err != nil {
-		return fmt.Errorf("cannot remove %s. Error: %w", aptCachePath, err)
+func (m *Manager) clearCache() error {
+	cachePath := m.cachePath()
+	if err := os.RemoveAll(cachePath); err != nil {
+		return fmt.Errorf("cannot remove %s. Error: %w", cachePath, err)
 	}
 	return nil
 }
 
 func extractPackage(p *bundle.Package, dir string) error {
+	if err := extractPackageFile(p, dir); err != nil {
+		return err
+	}
+	for _, dep := range p.Dependencies {
+		if err := extractPackage(dep, dir); err != nil {
+			return fmt.Errorf("cannot copy dependency %s of package %s to %s. Error: %w", dep.Path, p.Path, dir, err)
+		}
+	}
+	return nil
+}
+
+// extractPackageFile copies the package file without its dependencies to the directory.
+func extractPackageFile(p *bundle.Package, dir string) error {
 	from, err := p.Open()
 	if err != nil {
 		return fmt.Errorf("cannot open package %s. Error: %w", p.Path, err)
@@ -228,31 +410,82 @@ func extractPackage(p *bundle.Package, dir string) error {
 	if _, err := io.Copy(to, from); err != nil {
 		return fmt.Errorf("cannot copy package %s to %s. Error: %w", p.Path, dir, err)
 	}
-	for _, dep := range p.Dependencies {
-		if err := extractPackage(dep, dir); err != nil {
-			return fmt.Errorf("cannot copy dependency %s of package %s to %s. Error: %w", dep.Path, p.Path, dir, err)
-		}
-	}
 	return nil
 }
 
-func copyDebFilesFromAptCache(destDirPath string) error {
-	entries, err := os.ReadDir(aptCachePath)
+func (m *Manager) copyDebFilesFromCache(ctx context.Context, destDirPath string) error {
+	progress := bundle.ProgressFrom(ctx)
+	progress.SetPhase(bundle.PhaseCopy)
+	cachePath := m.cachePath()
+	entries, err := os.ReadDir(cachePath)
 	if err != nil {
-		return fmt.Errorf("cannot read dir %s. Error: %w", aptCachePath, err)
+		return fmt.Errorf("cannot read dir %s. Error: %w", cachePath, err)
 	}
 	for _, entry := range entries {
 		if entry.IsDir() || path.Ext(entry.Name()) != ".deb" {
 			continue
 		}
-		filePath := path.Join(aptCachePath, entry.Name())
+		filePath := path.Join(cachePath, entry.Name())
 		if err = copyFile(filePath, destDirPath); err != nil {
 			return err
 		}
+		if info, err := entry.Info(); err == nil {
+			progress.AddBytes(info.Size())
+		}
 	}
 	return nil
 }
 
+// downloadWatchInterval is how often the size of the APT cache is reported while APT downloads packages.
+const downloadWatchInterval = 500 * time.Millisecond
+
+// watchDownload reports the download phase and the size of the package files in the APT cache, including
+// the partially downloaded ones, to the progress tracker of the context until the returned function is called.
+func (m *Manager) watchDownload(ctx context.Context) func() {
+	progress := bundle.ProgressFrom(ctx)
+	if progress == nil {
+		return func() {}
+	}
+	progress.SetPhase(bundle.PhaseDownload)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(downloadWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				progress.SetBytes(m.cacheSize())
+				return
+			case <-ticker.C:
+				progress.SetBytes(m.cacheSize())
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// cacheSize returns the size of the package files in the APT cache.
+func (m *Manager) cacheSize() int64 {
+	var size int64
+	for _, dir := range []string{m.cachePath(), path.Join(m.cachePath(), "partial")} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && !entry.IsDir() {
+				size += info.Size()
+			}
+		}
+	}
+	return size
+}
+
 func copyFile(filePath, destDirPath string) error {
 	r, err := os.Open(filePath)
 	if err != nil {
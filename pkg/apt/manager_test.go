@@ -33,6 +33,14 @@ func TestManager_IsMainPackage(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "does not detect package with the directory name as prefix",
+			args: args{
+				packageDirName:  "docker-ce",
+				packageFileName: "docker-ce-cli_20.10.7~3-0~ubuntu-bionic_amd64.deb",
+			},
+			want: false,
+		},
 		{
 			name: "does not detect main package",
 			args: args{
@@ -59,20 +67,28 @@ func Test_parseDependencies(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
-		want []bundle.NameVersion
+		want [][]bundle.Relation
 	}{
 		{
 			name: "Find dependencies",
 			args: args{unmetDependenciesOutput},
-			want: []bundle.NameVersion{
-				{"kubelet", "1.13.0"},
-				{"kubectl", "1.13.0"},
+			want: [][]bundle.Relation{
+				{{Name: "kubelet", Operator: ">=", Version: "1.13.0"}},
+				{{Name: "kubectl", Operator: ">=", Version: "1.13.0"}},
 			},
 		},
 		{name: "Find dependencies without version",
 			args: args{singleDependency},
-			want: []bundle.NameVersion{
-				{Name: "libtomcrypt0"},
+			want: [][]bundle.Relation{
+				{{Name: "libtomcrypt0"}},
+			},
+		},
+		{name: "Find dependencies with alternatives and exact versions",
+			args: args{alternativeDependencies},
+			want: [][]bundle.Relation{
+				{{Name: "kubernetes-cni", Operator: "=", Version: "0.8.7-00"}},
+				{{Name: "ntp"}, {Name: "time-daemon"}},
+				{{Name: "libc6", Operator: ">=", Version: "2.28"}},
 			},
 		},
 	}
@@ -95,8 +111,44 @@ func Test_parseResultType(t *testing.T) {
 		want bundle.InstallResultType
 	}{
 		{
-			name: "Correctly parses unknown result",
+			name: "Correctly parses essential packages removal result",
 			args: args{essentialPackagesToBeRemovedOutput},
+			want: bundle.ResultEssentialRemoval,
+		},
+		{
+			name: "Correctly parses conflicts result",
+			args: args{conflictsOutput},
+			want: bundle.ResultConflicts,
+		},
+		{
+			name: "Correctly parses held packages result",
+			args: args{heldPackagesOutput},
+			want: bundle.ResultHeldBrokenPackages,
+		},
+		{
+			name: "Correctly parses lock contention result",
+			args: args{lockContentionOutput},
+			want: bundle.ResultLockContention,
+		},
+		{
+			name: "Correctly parses corrupt archive result",
+			args: args{corruptArchiveOutput},
+			want: bundle.ResultCorruptArchive,
+		},
+		{
+			name: "Correctly parses packages removal result",
+			args: args{packagesRemovedOutput},
+			want: bundle.ResultPackagesRemoved,
+		},
+		{
+			name: "Correctly parses held packages result with packages removal",
+			args: args{"The following packages will be REMOVED\n  ntp\n" +
+				"E: Unable to correct problems, you have held broken packages."},
+			want: bundle.ResultHeldBrokenPackages,
+		},
+		{
+			name: "Correctly parses unknown result",
+			args: args{"E: Something unexpected happened"},
 			want: bundle.ResultUnknownProblem,
 		},
 		{
@@ -123,3 +175,150 @@ func Test_parseResultType(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseMadison(t *testing.T) {
+	want := []string{"3.2-4ubuntu4.5", "3.2-4ubuntu4"}
+	if got := parseMadison(madisonOutput); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMadison() = %v, want %v", got, want)
+	}
+}
+
+func TestManager_ParseArchitecture(t *testing.T) {
+	tests := []struct {
+		name            string
+		packageFileName string
+		want            string
+	}{
+		{name: "amd64 package", packageFileName: "containerd.io_1.4.7-1_amd64.deb", want: "amd64"},
+		{name: "arm64 package", packageFileName: "kubeadm_1.20.11-00_arm64.deb", want: "arm64"},
+		{name: "architecture-independent package", packageFileName: "apt-transport-https_1.6.14_all.deb", want: "all"},
+		{name: "package directory", packageFileName: "kubeadm=1.20.11-00", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Manager{}
+			if got := m.ParseArchitecture(tt.packageFileName); got != tt.want {
+				t.Errorf("ParseArchitecture() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseRelations(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		want  [][]bundle.Relation
+	}{
+		{
+			name:  "parses versioned relations",
+			field: "libc6 (>= 2.14), libseccomp2 (>= 2.4.1)",
+			want: [][]bundle.Relation{
+				{{Name: "libc6", Operator: ">=", Version: "2.14"}},
+				{{Name: "libseccomp2", Operator: ">=", Version: "2.4.1"}},
+			},
+		},
+		{
+			name:  "parses alternatives, architecture qualifiers and restrictions",
+			field: "debconf (>= 0.5) | debconf-2.0, python3:any [amd64], kubernetes-cni (<<0.8.7)",
+			want: [][]bundle.Relation{
+				{{Name: "debconf", Operator: ">=", Version: "0.5"}, {Name: "debconf-2.0"}},
+				{{Name: "python3"}},
+				{{Name: "kubernetes-cni", Operator: "<<", Version: "0.8.7"}},
+			},
+		},
+		{
+			name:  "parses empty field",
+			field: "",
+			want:  [][]bundle.Relation{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRelations(tt.field); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRelations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseResultDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want []string
+	}{
+		{
+			name: "Finds removed essential packages",
+			msg:  essentialPackagesToBeRemovedOutput,
+			want: []string{"apt"},
+		},
+		{
+			name: "Finds conflicts",
+			msg:  conflictsOutput,
+			want: []string{
+				"containerd.io : Conflicts: containerd",
+				"Conflicts: runc",
+				"docker.io : Breaks: containerd.io (< 1.5)",
+			},
+		},
+		{
+			name: "Finds held packages",
+			msg:  heldPackagesOutput,
+			want: []string{"kubelet", "kubectl"},
+		},
+		{
+			name: "Finds lock file",
+			msg:  lockContentionOutput,
+			want: []string{"/var/lib/dpkg/lock-frontend"},
+		},
+		{
+			name: "Finds removed packages",
+			msg:  packagesRemovedOutput,
+			want: []string{"ntp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseResultDetails(parseResultType(tt.msg), tt.msg)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseResultDetails() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourcesVendor(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		want    string
+	}{
+		{
+			name:    "ubuntu mirror",
+			sources: []string{"deb [arch=amd64] http://archive.ubuntu.com/ubuntu bionic main"},
+			want:    "ubuntu",
+		},
+		{
+			name:    "debian security mirror",
+			sources: []string{"deb http://security.debian.org/debian-security buster/updates main"},
+			want:    "debian",
+		},
+		{
+			name:    "local mirror of ubuntu",
+			sources: []string{"deb http://mirror.local/repo stable main", "deb http://mirror.local/ubuntu bionic main"},
+			want:    "ubuntu",
+		},
+		{
+			name:    "unknown",
+			sources: []string{"deb http://mirror.local/repo stable main"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SourcesVendor(tt.sources); got != tt.want {
+				t.Errorf("SourcesVendor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package apt
+
+import (
+	"regexp"
+	"strings"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+var lockMessages = []string{
+	"could not get lock",
+	"unable to lock",
+	"unable to acquire the dpkg frontend lock",
+}
+
+var corruptArchiveMessages = []string{
+	"invalid archive signature",
+	"could not read meta data",
+	"could not locate member",
+	"not a debian format archive",
+	"unexpected end of file",
+	"is corrupt",
+}
+
+// conflictReg matches unmet relations that are not dependencies, for example " kubelet : Breaks: kubeadm (< 1.13)".
+var conflictReg = regexp.MustCompile(`^\s*(?:\S+\s+:\s+)?(?:Conflicts|Breaks):\s*(.+)$`)
+
+var lockReg = regexp.MustCompile(`(?i)(?:could not get lock|unable to lock[^/]*)\s+(/\S+)`)
+
+func parseResultType(msg string) bundle.InstallResultType {
+	msg = strings.ToLower(msg)
+	if containsAny(msg, lockMessages) {
+		return bundle.ResultLockContention
+	}
+	if containsAny(msg, corruptArchiveMessages) {
+		return bundle.ResultCorruptArchive
+	}
+	if strings.Contains(msg, "essential packages will be removed") ||
+		strings.Contains(msg, "essential packages were removed") {
+		return bundle.ResultEssentialRemoval
+	}
+	if strings.Contains(msg, "will be downgraded") {
+		return bundle.ResultNewerAlreadyInstalled
+	}
+	if strings.Contains(msg, "unmet dependencies") {
+		if strings.Contains(msg, "depends:") {
+			return bundle.ResultUnmetDependencies
+		}
+		if strings.Contains(msg, "conflicts:") || strings.Contains(msg, "breaks:") {
+			return bundle.ResultConflicts
+		}
+	}
+	if strings.Contains(msg, "held broken packages") || strings.Contains(msg, "held packages were changed") {
+		return bundle.ResultHeldBrokenPackages
+	}
+	if strings.Contains(msg, "unable to locate package") {
+		return bundle.ResultCannotFindPackage
+	}
+	// apt-get lists the packages to remove in the output of the failures with more specific causes too,
+	// so removals are checked last.
+	if strings.Contains(msg, "packages will be removed") {
+		return bundle.ResultPackagesRemoved
+	}
+	return bundle.ResultUnknownProblem
+}
+
+// parseResultDetails extracts the details of the result from the apt-get output.
+func parseResultDetails(result bundle.InstallResultType, msg string) []string {
+	switch result {
+	case bundle.ResultEssentialRemoval:
+		return parseList(msg, "essential packages will be removed")
+	case bundle.ResultPackagesRemoved:
+		return parseList(msg, "packages will be removed")
+	case bundle.ResultHeldBrokenPackages:
+		if held := parseList(msg, "held packages will be changed"); len(held) > 0 {
+			return held
+		}
+		return parseErrors(msg)
+	case bundle.ResultConflicts:
+		details := make([]string, 0)
+		for _, line := range strings.Split(msg, "\n") {
+			if conflictReg.MatchString(line) {
+				details = append(details, strings.TrimSpace(line))
+			}
+		}
+		return details
+	case bundle.ResultLockContention:
+		details := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, l := range lockReg.FindAllStringSubmatch(msg, -1) {
+			lockFile := strings.TrimRight(l[1], ".,)")
+			if !seen[lockFile] {
+				seen[lockFile] = true
+				details = append(details, lockFile)
+			}
+		}
+		return details
+	case bundle.ResultCorruptArchive:
+		return parseErrors(msg)
+	}
+	return nil
+}
+
+// parseList returns the package names listed after the header line that contains the given text. apt-get
+// indents the list with two spaces. The essential packages list goes after the "This should NOT be done" warning.
+func parseList(msg, header string) []string {
+	names := make([]string, 0)
+	lines := strings.Split(msg, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !strings.Contains(strings.ToLower(lines[i]), header) {
+			continue
+		}
+		for i++; i < len(lines); i++ {
+			if strings.HasPrefix(lines[i], "This should NOT be done") {
+				continue
+			}
+			if !strings.HasPrefix(lines[i], "  ") {
+				break
+			}
+			names = append(names, strings.Fields(lines[i])...)
+		}
+		break
+	}
+	return names
+}
+
+// parseErrors returns the error lines of the apt-get output.
+func parseErrors(msg string) []string {
+	errors := make([]string, 0)
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.HasPrefix(line, "E: ") {
+			errors = append(errors, strings.TrimPrefix(line, "E: "))
+		}
+	}
+	return errors
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, ss := range substrings {
+		if strings.Contains(s, ss) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package apt
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+const hostAPTConfigPath = "/etc/apt"
+
+// vendorDomains are the distros the package sources may belong to with the domains of their mirrors.
+var vendorDomains = []struct{ vendor, domain string }{
+	{"ubuntu", "ubuntu.com"},
+	{"debian", "debian.org"},
+}
+
+// rootDirs are the directories APT and dpkg expect to find in an isolated root.
+var rootDirs = []string{
+	"etc/apt/apt.conf.d",
+	"etc/apt/preferences.d",
+	"etc/apt/sources.list.d",
+	"etc/apt/trusted.gpg.d",
+	"var/lib/apt/lists/partial",
+	"var/cache/apt/archives/partial",
+	"var/lib/dpkg",
+}
+
+// setupRoot creates an isolated APT root with the configured or host package sources and keyrings or without
+// sources at all, the dpkg status of the installed packages and the manager architecture, and then downloads
+// the package lists into it.
+func (m *Manager) setupRoot(ctx context.Context, cfg Config) error {
+	m.root = path.Join(m.tmpDir, "root")
+	for _, d := range rootDirs {
+		if err := os.MkdirAll(path.Join(m.root, d), 0755); err != nil {
+			return fmt.Errorf("cannot create directory %s in APT root. Error: %w", d, err)
+		}
+	}
+	// Empty status means that nothing is installed in the root yet.
+	if err := os.WriteFile(m.statusPath(), []byte(cfg.Status), 0644); err != nil {
+		return fmt.Errorf("cannot create dpkg status file in APT root. Error: %w", err)
+	}
+	aptConfigPath := path.Join(m.root, "etc/apt")
+	switch {
+	case cfg.Offline:
+		// No sources, so the package lists stay empty.
+	case len(cfg.Sources) == 0:
+		if err := copyHostAPTConfig(aptConfigPath); err != nil {
+			return err
+		}
+	default:
+		if err := writeAPTConfig(aptConfigPath, cfg.Sources, cfg.Keyrings); err != nil {
+			return err
+		}
+	}
+	cmd := exec.Command("dpkg", "--admindir="+path.Dir(m.statusPath()), "--add-architecture", m.architecture)
+	if msg, err := combinedOutput(ctx, cmd); err != nil {
+		return fmt.Errorf("cannot add architecture %s to dpkg in APT root. Command output:\n%s. Error: %w",
+			m.architecture, string(msg), err)
+	}
+	if cfg.Offline {
+		return nil
+	}
+	return m.updatePackageLists(ctx)
+}
+
+func (m *Manager) updatePackageLists(ctx context.Context) error {
+	ctx, cancel := m.operationContext(ctx)
+	defer cancel()
+	msg, err := combinedOutput(ctx, m.aptCommand("apt-get", "update"))
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return fmt.Errorf("cannot run apt-get command. Error: %w", err)
+		}
+		return fmt.Errorf("cannot update package lists with apt-get update. Command ouput:\n%s", string(msg))
+	}
+	return nil
+}
+
+// aptCommand creates a shell command that launches the APT tool with the manager options.
+// Shell allows to use globs in the arguments.
+func (m *Manager) aptCommand(tool, args string) *exec.Cmd {
+	return exec.Command("sh", "-c", strings.Join(append(append([]string{tool}, m.aptOptions()...), args), " "))
+}
+
+func (m *Manager) aptOptions() []string {
+	if m.root == "" {
+		return nil
+	}
+	return []string{
+		"-o", "Dir=" + m.root,
+		"-o", "Dir::State::status=" + m.statusPath(),
+		"-o", "APT::Architecture=" + m.architecture,
+		"-o", "APT::Architectures=" + m.architecture,
+	}
+}
+
+func (m *Manager) cachePath() string {
+	if m.root == "" {
+		return aptCachePath
+	}
+	return path.Join(m.root, aptCachePath)
+}
+
+func (m *Manager) statusPath() string {
+	return path.Join(m.root, "var/lib/dpkg/status")
+}
+
+func nativeArchitecture() (string, error) {
+	msg, err := exec.Command("dpkg", "--print-architecture").Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot detect the native architecture with dpkg. Error: %w", err)
+	}
+	return strings.TrimSpace(string(msg)), nil
+}
+
+// writeAPTConfig writes the package sources and copies the keyrings to the APT configuration directory.
+func writeAPTConfig(destAPTConfigPath string, sources, keyrings []string) error {
+	sourcesListPath := path.Join(destAPTConfigPath, "sources.list")
+	if err := os.WriteFile(sourcesListPath, []byte(strings.Join(sources, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("cannot write %s. Error: %w", sourcesListPath, err)
+	}
+	for _, k := range keyrings {
+		if err := copyFile(k, path.Join(destAPTConfigPath, "trusted.gpg.d")); err != nil {
+			return fmt.Errorf("cannot copy keyring %s. Error: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// copyHostAPTConfig copies the host package sources and keyrings to the APT configuration directory.
+func copyHostAPTConfig(destAPTConfigPath string) error {
+	files := []string{"sources.list", "trusted.gpg"}
+	for _, f := range files {
+		filePath := path.Join(hostAPTConfigPath, f)
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyFile(filePath, destAPTConfigPath); err != nil {
+			return err
+		}
+	}
+	for _, d := range []string{"sources.list.d", "trusted.gpg.d"} {
+		dirPath := path.Join(hostAPTConfigPath, d)
+		entries, err := os.ReadDir(dirPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read dir %s. Error: %w", dirPath, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if err := copyFile(path.Join(dirPath, entry.Name()), path.Join(destAPTConfigPath, d)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Sources returns the APT source lines the manager resolves packages with. Sources of deb822 .sources files
+// are converted to one-line style.
+func (m *Manager) Sources() ([]string, error) {
+	aptConfigPath := hostAPTConfigPath
+	if m.root != "" {
+		aptConfigPath = path.Join(m.root, "etc/apt")
+	}
+	files := []string{path.Join(aptConfigPath, "sources.list")}
+	sourcesDirPath := path.Join(aptConfigPath, "sources.list.d")
+	entries, err := os.ReadDir(sourcesDirPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("cannot read dir %s. Error: %w", sourcesDirPath, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, path.Join(sourcesDirPath, entry.Name()))
+		}
+	}
+	sources := make([]string, 0)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read APT sources %s. Error: %w", f, err)
+		}
+		switch path.Ext(f) {
+		case ".list":
+			sources = append(sources, parseSourcesList(string(data))...)
+		case ".sources":
+			sources = append(sources, parseDeb822Sources(string(data))...)
+		}
+	}
+	return sources, nil
+}
+
+// SourcesVendor returns the distro of the APT sources, "ubuntu" or "debian", by the mirror host names and paths.
+// It returns an empty string when none of the sources tells the distro.
+func SourcesVendor(sources []string) string {
+	for _, source := range sources {
+		for _, field := range strings.Fields(source) {
+			u, err := url.Parse(field)
+			if err != nil || u.Scheme == "" {
+				continue
+			}
+			host := strings.ToLower(u.Hostname())
+			segments := strings.Split(strings.ToLower(u.Path), "/")
+			for _, v := range vendorDomains {
+				if host == v.domain || strings.HasSuffix(host, "."+v.domain) {
+					return v.vendor
+				}
+				for _, segment := range segments {
+					if segment == v.vendor || strings.HasPrefix(segment, v.vendor+"-") {
+						return v.vendor
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func parseSourcesList(list string) []string {
+	sources := make([]string, 0)
+	for _, line := range strings.Split(list, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			sources = append(sources, line)
+		}
+	}
+	return sources
+}
+
+func parseDeb822Sources(data string) []string {
+	sources := make([]string, 0)
+	for _, stanza := range strings.Split(data, "\n\n") {
+		fields := parseControlFields(stanza)
+		if fields["Types"] == "" || fields["URIs"] == "" || strings.EqualFold(fields["Enabled"], "no") {
+			continue
+		}
+		for _, t := range strings.Fields(fields["Types"]) {
+			for _, uri := range strings.Fields(fields["URIs"]) {
+				for _, suite := range strings.Fields(fields["Suites"]) {
+					sources = append(sources, strings.TrimSpace(strings.Join([]string{t, uri, suite,
+						fields["Components"]}, " ")))
+				}
+			}
+		}
+	}
+	return sources
+}
@@ -0,0 +1,186 @@
+package apt
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+// ErrReleaseRequired is returned when the USN security advisories are read without the distro release.
+var ErrReleaseRequired = errors.New("cannot read USN security advisories without the distro release, " +
+	"because the USN database covers all releases")
+
+// ReadAdvisories reads the security advisories from an Ubuntu CVE OVAL XML file or a USN database JSON file.
+// The USN database covers all releases, so only the advisories for the release are read and the release is
+// required for it.
+func ReadAdvisories(r io.Reader, release string) ([]bundle.Advisory, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read security advisories. Error: %w", err)
+	}
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("<")):
+		return parseOVAL(data)
+	case bytes.HasPrefix(data, []byte("{")):
+		return parseUSN(data, release)
+	default:
+		return nil, fmt.Errorf("cannot read security advisories. Expected OVAL XML or USN JSON")
+	}
+}
+
+type ovalDefinitions struct {
+	Definitions []ovalDefinition `xml:"definitions>definition"`
+}
+
+type ovalDefinition struct {
+	Class      string          `xml:"class,attr"`
+	ID         string          `xml:"id,attr"`
+	References []ovalReference `xml:"metadata>reference"`
+	Severity   string          `xml:"metadata>advisory>severity"`
+	Criteria   ovalCriteria    `xml:"criteria"`
+}
+
+type ovalReference struct {
+	Source string `xml:"source,attr"`
+	RefID  string `xml:"ref_id,attr"`
+}
+
+type ovalCriteria struct {
+	Criteria   []ovalCriteria  `xml:"criteria"`
+	Criterions []ovalCriterion `xml:"criterion"`
+}
+
+type ovalCriterion struct {
+	Comment string `xml:"comment,attr"`
+}
+
+var (
+	ovalFixedReg = regexp.MustCompile(
+		`^(\S+) package in \S+ (?:was vulnerable but )?has been fixed \(note: '([^']+)'\)`)
+	ovalAffectedReg = regexp.MustCompile(`^(\S+) package in \S+ is affected and (?:may )?needs? fixing`)
+)
+
+// parseOVAL reads the vulnerability definitions of an Ubuntu CVE OVAL file. The affected source packages and
+// their fixed versions come from the criterion comments, for example "openssl package in bionic was vulnerable
+// but has been fixed (note: '1.1.1-1ubuntu2.1~18.04.8')."
+func parseOVAL(data []byte) ([]bundle.Advisory, error) {
+	var definitions ovalDefinitions
+	if err := xml.Unmarshal(data, &definitions); err != nil {
+		return nil, fmt.Errorf("cannot parse OVAL security advisories. Error: %w", err)
+	}
+	advisories := make([]bundle.Advisory, 0)
+	for _, d := range definitions.Definitions {
+		if d.Class != "vulnerability" {
+			continue
+		}
+		var cves []string
+		for _, r := range d.References {
+			if r.Source == "CVE" {
+				cves = append(cves, r.RefID)
+			}
+		}
+		id := d.ID
+		if len(cves) > 0 {
+			id = cves[0]
+		}
+		for _, c := range ovalComments(d.Criteria) {
+			a := bundle.Advisory{ID: id, CVEs: cves, Severity: d.Severity, Source: true}
+			if m := ovalFixedReg.FindStringSubmatch(c); m != nil {
+				a.Package, a.FixedVersion = m[1], m[2]
+			} else if m = ovalAffectedReg.FindStringSubmatch(c); m != nil {
+				a.Package = m[1]
+			} else {
+				continue
+			}
+			advisories = append(advisories, a)
+		}
+	}
+	return advisories, nil
+}
+
+func ovalComments(c ovalCriteria) []string {
+	comments := make([]string, 0, len(c.Criterions))
+	for _, criterion := range c.Criterions {
+		comments = append(comments, criterion.Comment)
+	}
+	for _, nested := range c.Criteria {
+		comments = append(comments, ovalComments(nested)...)
+	}
+	return comments
+}
+
+type usnNotice struct {
+	ID       string                `json:"id"`
+	CVEs     []string              `json:"cves"`
+	Releases map[string]usnRelease `json:"releases"`
+}
+
+type usnRelease struct {
+	Sources     map[string]usnPackage `json:"sources"`
+	Binaries    map[string]usnPackage `json:"binaries"`
+	AllBinaries map[string]usnPackage `json:"allbinaries"`
+}
+
+type usnPackage struct {
+	Version string `json:"version"`
+}
+
+// parseUSN reads the Ubuntu Security Notices database, a JSON object of notices by their IDs. It prefers
+// the complete list of the fixed binary packages and falls back to the source packages.
+func parseUSN(data []byte, release string) ([]bundle.Advisory, error) {
+	// The fixed versions differ between the releases, so the advisories of all releases would be wrong.
+	if release == "" {
+		return nil, ErrReleaseRequired
+	}
+	var notices map[string]usnNotice
+	if err := json.Unmarshal(data, &notices); err != nil {
+		return nil, fmt.Errorf("cannot parse USN security advisories. Error: %w", err)
+	}
+	ids := make([]string, 0, len(notices))
+	for id := range notices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	advisories := make([]bundle.Advisory, 0)
+	for _, id := range ids {
+		n := notices[id]
+		if n.ID != "" {
+			id = n.ID
+		}
+		if !strings.HasPrefix(id, "USN-") {
+			id = "USN-" + id
+		}
+		for name, r := range n.Releases {
+			if name != release {
+				continue
+			}
+			packages, source := r.AllBinaries, false
+			if len(packages) == 0 {
+				packages = r.Binaries
+			}
+			if len(packages) == 0 {
+				packages, source = r.Sources, true
+			}
+			for p, v := range packages {
+				advisories = append(advisories, bundle.Advisory{ID: id, CVEs: n.CVEs, Package: p, Source: source,
+					FixedVersion: v.Version})
+			}
+		}
+	}
+	sort.SliceStable(advisories, func(i, j int) bool {
+		if advisories[i].ID != advisories[j].ID {
+			return advisories[i].ID < advisories[j].ID
+		}
+		return advisories[i].Package < advisories[j].Package
+	})
+	return advisories, nil
+}
@@ -0,0 +1,65 @@
+package apt
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+func TestReadAdvisories(t *testing.T) {
+	usnCVEs := []string{"CVE-2021-23840", "CVE-2021-23841"}
+	tests := []struct {
+		name    string
+		feed    string
+		release string
+		want    []bundle.Advisory
+	}{
+		{
+			name: "OVAL",
+			feed: ovalAdvisories,
+			want: []bundle.Advisory{
+				{ID: "CVE-2021-23840", CVEs: []string{"CVE-2021-23840"}, Severity: "Medium", Package: "openssl",
+					Source: true, FixedVersion: "1.1.1-1ubuntu2.1~18.04.8"},
+				{ID: "CVE-2021-23840", CVEs: []string{"CVE-2021-23840"}, Severity: "Medium", Package: "openssl1.0",
+					Source: true},
+			},
+		},
+		{
+			name:    "USN binaries",
+			feed:    usnAdvisories,
+			release: "bionic",
+			want: []bundle.Advisory{
+				{ID: "USN-4738-1", CVEs: usnCVEs, Package: "libssl1.1", FixedVersion: "1.1.1-1ubuntu2.1~18.04.8"},
+				{ID: "USN-4738-1", CVEs: usnCVEs, Package: "openssl", FixedVersion: "1.1.1-1ubuntu2.1~18.04.8"},
+			},
+		},
+		{
+			name:    "USN sources",
+			feed:    usnAdvisories,
+			release: "focal",
+			want: []bundle.Advisory{
+				{ID: "USN-4738-1", CVEs: usnCVEs, Package: "openssl", Source: true, FixedVersion: "1.1.1f-1ubuntu2.2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadAdvisories(strings.NewReader(tt.feed), tt.release)
+			if err != nil {
+				t.Fatalf("ReadAdvisories() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadAdvisories() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAdvisories_USNWithoutRelease(t *testing.T) {
+	if _, err := ReadAdvisories(strings.NewReader(usnAdvisories), ""); !errors.Is(err, ErrReleaseRequired) {
+		t.Errorf("ReadAdvisories() error = %v, want %v", err, ErrReleaseRequired)
+	}
+}
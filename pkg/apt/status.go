@@ -0,0 +1,93 @@
+package apt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadInstalledPackages reads the installed packages of a node and returns their versions by name.
+// It accepts either a dpkg status file (/var/lib/dpkg/status) or a package list with one "name version"
+// or "name=version" pair per line, for example the output of dpkg-query -W.
+func ReadInstalledPackages(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read installed packages. Error: %w", err)
+	}
+	if isDpkgStatus(string(data)) {
+		return parseDpkgStatus(string(data)), nil
+	}
+	return parsePackageList(string(data))
+}
+
+// ReadInstalledStatus reads the dpkg status file of a node (/var/lib/dpkg/status) as it is, so that APT knows
+// every field of the installed packages, for example Provides, Essential and Multi-Arch. A package list has
+// only names and versions, so it's rejected.
+func ReadInstalledStatus(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("cannot read installed packages. Error: %w", err)
+	}
+	if !isDpkgStatus(string(data)) {
+		return "", fmt.Errorf("installed packages are not a dpkg status file")
+	}
+	return strings.TrimRight(string(data), "\n") + "\n", nil
+}
+
+func isDpkgStatus(data string) bool {
+	return strings.HasPrefix(data, "Package:") || strings.Contains(data, "\nPackage:")
+}
+
+func parseDpkgStatus(status string) map[string]string {
+	installed := make(map[string]string)
+	for _, stanza := range strings.Split(status, "\n\n") {
+		fields := parseControlFields(stanza)
+		if fields["Package"] == "" || !strings.HasSuffix(fields["Status"], " installed") {
+			continue
+		}
+		// Strip architecture qualifier of Multi-Arch packages
+		name := strings.Split(fields["Package"], ":")[0]
+		installed[name] = fields["Version"]
+	}
+	return installed
+}
+
+func parsePackageList(list string) (map[string]string, error) {
+	installed := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Fields(strings.Replace(line, "=", " ", 1))
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("cannot parse installed package \"%s\". Expected name and version", line)
+		}
+		installed[strings.Split(parts[0], ":")[0]] = parts[1]
+	}
+	return installed, nil
+}
+
+// parseControlFields parses a stanza of Debian control data. Continuation lines are joined to their fields.
+func parseControlFields(stanza string) map[string]string {
+	fields := make(map[string]string)
+	var last string
+	for _, line := range strings.Split(stanza, "\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && last != "" {
+			fields[last] += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		last = line[:i]
+		fields[last] = strings.TrimSpace(line[i+1:])
+	}
+	return fields
+}
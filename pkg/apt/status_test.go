@@ -0,0 +1,61 @@
+package apt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadInstalledPackages(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "reads dpkg status",
+			input: dpkgStatus,
+			want:  map[string]string{"socat": "1.7.3.2-2ubuntu2", "libseccomp2": "2.4.1-0ubuntu0.18.04.2"},
+		},
+		{
+			name:  "reads package list",
+			input: "socat\t1.7.3.2-2ubuntu2\nlibseccomp2:amd64 2.4.1-0ubuntu0.18.04.2\n\nconntrack=1:1.4.4+snapshot20161117-6ubuntu2\n",
+			want: map[string]string{
+				"socat":       "1.7.3.2-2ubuntu2",
+				"libseccomp2": "2.4.1-0ubuntu0.18.04.2",
+				"conntrack":   "1:1.4.4+snapshot20161117-6ubuntu2",
+			},
+		},
+		{
+			name:    "rejects package without version",
+			input:   "socat\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadInstalledPackages(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadInstalledPackages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadInstalledPackages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadInstalledStatus(t *testing.T) {
+	got, err := ReadInstalledStatus(strings.NewReader(dpkgStatus))
+	if err != nil {
+		t.Fatalf("ReadInstalledStatus() error = %v", err)
+	}
+	// The status keeps the fields APT needs besides the version, for example Multi-Arch.
+	if got != dpkgStatus+"\n" {
+		t.Errorf("ReadInstalledStatus() = %q, want %q", got, dpkgStatus+"\n")
+	}
+	if _, err = ReadInstalledStatus(strings.NewReader("socat 1.7.3.2-2ubuntu2\n")); err == nil {
+		t.Errorf("ReadInstalledStatus() error = nil, want an error for a package list")
+	}
+}
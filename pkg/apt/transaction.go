@@ -0,0 +1,48 @@
+package apt
+
+import (
+	"regexp"
+	"strings"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+// transactionReg matches the transaction lines of apt-get -s output, for example
+// "Inst libseccomp2 [2.4.1-0ubuntu0.18.04.2] (2.5.1-1ubuntu1~18.04.1 Ubuntu:18.04/bionic-updates [amd64]) []".
+var transactionReg = regexp.MustCompile(`^(Inst|Conf|Remv|Purg) (\S+)(?: \[([^\]]*)\])?(?: \((\S+)(?: (.*?))? \[([^\]]+)\]\))?`)
+
+// parseTransaction parses the simulated transaction from the apt-get -s output.
+func parseTransaction(msg string) bundle.Transaction {
+	t := bundle.Transaction{}
+	for _, line := range strings.Split(msg, "\n") {
+		l := transactionReg.FindStringSubmatch(line)
+		if l == nil {
+			continue
+		}
+		c := bundle.Change{
+			Name:         strings.Split(l[2], ":")[0],
+			OldVersion:   l[3],
+			NewVersion:   l[4],
+			Origin:       l[5],
+			Architecture: l[6],
+		}
+		switch l[1] {
+		case "Conf":
+			t.ConfigureOrder = append(t.ConfigureOrder, c.Name)
+		case "Remv", "Purg":
+			t.Removals = append(t.Removals, c)
+		case "Inst":
+			switch {
+			case c.OldVersion == "":
+				t.Installs = append(t.Installs, c)
+			case compareVersions(c.NewVersion, c.OldVersion) > 0:
+				t.Upgrades = append(t.Upgrades, c)
+			case compareVersions(c.NewVersion, c.OldVersion) < 0:
+				t.Downgrades = append(t.Downgrades, c)
+			default:
+				t.Reinstalls = append(t.Reinstalls, c)
+			}
+		}
+	}
+	return t
+}
@@ -0,0 +1,36 @@
+package apt
+
+import (
+	"reflect"
+	"testing"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+func Test_parseTransaction(t *testing.T) {
+	want := bundle.Transaction{
+		Installs: []bundle.Change{
+			{Name: "conntrack", NewVersion: "1:1.4.4+snapshot20161117-6ubuntu2", Origin: "Ubuntu:18.04/bionic",
+				Architecture: "amd64"},
+			{Name: "kubernetes-cni", NewVersion: "0.8.7-00", Origin: "local-deb", Architecture: "amd64"},
+			{Name: "socat", NewVersion: "1.7.3.2-2ubuntu2", Origin: "Ubuntu:18.04/bionic, Ubuntu:18.04/bionic-security",
+				Architecture: "amd64"},
+			{Name: "kubelet", NewVersion: "1.20.11-00", Origin: "local-deb", Architecture: "amd64"},
+		},
+		Upgrades: []bundle.Change{
+			{Name: "libseccomp2", OldVersion: "2.4.1-0ubuntu0.18.04.2", NewVersion: "2.5.1-1ubuntu1~18.04.1",
+				Origin: "Ubuntu:18.04/bionic-updates", Architecture: "amd64"},
+		},
+		Downgrades: []bundle.Change{
+			{Name: "containerd.io", OldVersion: "1.4.9-1", NewVersion: "1.4.7-1", Origin: "local-deb",
+				Architecture: "amd64"},
+		},
+		Removals: []bundle.Change{
+			{Name: "ntp", OldVersion: "1:4.2.8p10+dfsg-5ubuntu7.3"},
+		},
+		ConfigureOrder: []string{"libseccomp2", "containerd.io", "conntrack", "kubernetes-cni", "socat", "kubelet"},
+	}
+	if got := parseTransaction(transactionOutput); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTransaction() = %+v, want %+v", got, want)
+	}
+}
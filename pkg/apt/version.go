@@ -0,0 +1,118 @@
+package apt
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares two Debian package versions as dpkg does.
+// It returns -1 if a < b, 0 if a == b and 1 if a > b.
+func compareVersions(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitVersion(a)
+	bEpoch, bUpstream, bRevision := splitVersion(b)
+	if aEpoch != bEpoch {
+		if aEpoch < bEpoch {
+			return -1
+		}
+		return 1
+	}
+	if c := compareVersionPart(aUpstream, bUpstream); c != 0 {
+		return c
+	}
+	return compareVersionPart(aRevision, bRevision)
+}
+
+// splitVersion splits a Debian version to epoch, upstream version and Debian revision.
+func splitVersion(v string) (int, string, string) {
+	epoch := 0
+	if i := strings.Index(v, ":"); i >= 0 {
+		if e, err := strconv.Atoi(v[:i]); err == nil {
+			epoch = e
+		}
+		v = v[i+1:]
+	}
+	revision := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		revision = v[i+1:]
+		v = v[:i]
+	}
+	return epoch, v, revision
+}
+
+func compareVersionPart(a, b string) int {
+	for a != "" || b != "" {
+		var aNonDigits, bNonDigits string
+		aNonDigits, a = splitPrefix(a, false)
+		bNonDigits, b = splitPrefix(b, false)
+		if c := compareNonDigits(aNonDigits, bNonDigits); c != 0 {
+			return c
+		}
+		var aDigits, bDigits string
+		aDigits, a = splitPrefix(a, true)
+		bDigits, b = splitPrefix(b, true)
+		if c := compareDigits(aDigits, bDigits); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func splitPrefix(s string, digits bool) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func compareNonDigits(a, b string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var ac, bc int
+		if i < len(a) {
+			ac = charOrder(a[i])
+		}
+		if i < len(b) {
+			bc = charOrder(b[i])
+		}
+		if ac != bc {
+			if ac < bc {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// charOrder returns the weight of a character in a non-digit part of a version.
+// Tilde sorts before anything, even the end of a part, letters sort before non-letters.
+func charOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case isLetter(c):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,31 @@
+package apt
+
+import "testing"
+
+func Test_compareVersions(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{a: "1.20.11-00", b: "1.20.11-00", want: 0},
+		{a: "1.20.11-00", b: "1.20.2-00", want: 1},
+		{a: "1.4.7-1", b: "1.4.10-1", want: -1},
+		{a: "1:1.2.8-9ubuntu12.3", b: "2.0-1", want: 1},
+		{a: "2.5.1-1ubuntu1~16.04.1", b: "2.5.1-1ubuntu1", want: -1},
+		{a: "3.2-4ubuntu4.5", b: "3.2-4ubuntu4", want: 1},
+		{a: "1.0a", b: "1.0+", want: -1},
+		{a: "1.0", b: "1.0-0", want: 0},
+		{a: "4.3.0+nmu1ubuntu1.1", b: "4.3.0", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := compareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareVersions(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := compareVersions(tt.b, tt.a); got != -tt.want {
+				t.Errorf("compareVersions(%q, %q) = %v, want %v", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+const (
+	progressAuto  = "auto"
+	progressBar   = "bar"
+	progressLines = "lines"
+	progressNone  = "none"
+	// progressBarWidth is the width of the progress bar in characters.
+	progressBarWidth = 30
+	// progressLineInterval is how often the line renderer writes the downloaded bytes.
+	progressLineInterval = 10 * time.Second
+)
+
+// progressRenderer renders the progress of a fix run.
+type progressRenderer interface {
+	bundle.ProgressReporter
+	// Finish completes rendering of the run.
+	Finish()
+}
+
+// newProgressRenderer creates the renderer of the progress mode. It returns nil for no progress. The auto mode
+// draws the bar on a terminal and writes lines otherwise.
+func newProgressRenderer(mode string) (progressRenderer, error) {
+	if mode == progressAuto {
+		mode = progressLines
+		if isTerminal(os.Stdout) {
+			mode = progressBar
+		}
+	}
+	switch mode {
+	case progressBar:
+		return bundle.NewBarRenderer(os.Stdout, progressBarWidth), nil
+	case progressLines:
+		return bundle.NewLineRenderer(os.Stdout, progressLineInterval), nil
+	case progressNone:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown progress mode %s, expected one of %s, %s, %s, %s", mode, progressAuto,
+		progressBar, progressLines, progressNone)
+}
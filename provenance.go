@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"konvoy-os-package-builder/bundle"
+	"konvoy-os-package-builder/pkg/apt"
+)
+
+// version is the tool version. It's set at build time with -ldflags "-X main.version=<version>".
+var version = "dev"
+
+// provenanceSuffix is appended to the output tarball path to get the default provenance path.
+const provenanceSuffix = ".intoto.json"
+
+// provenanceConfig configures the provenance of the run.
+type provenanceConfig struct {
+	// path is the file to write the provenance to. Empty means the output tarball path with provenanceSuffix.
+	path string
+	// parameters are the command line options of the run.
+	parameters map[string]string
+	// materials are the input files of the run.
+	materials []string
+}
+
+// writeProvenance writes the provenance of the fixed bundle written to the output tarball.
+func writeProvenance(b *bundle.Bundle, m *apt.Manager, cfg buildConfig, started time.Time) error {
+	p, err := bundle.NewProvenance(b)
+	if err != nil {
+		return fmt.Errorf("cannot create the provenance. Error: %w", err)
+	}
+	p.ToolVersion = version
+	p.HostRelease = osReleaseField("PRETTY_NAME")
+	p.Started = started
+	if p.Sources, err = m.Sources(); err != nil {
+		return err
+	}
+	for k, v := range cfg.provenance.parameters {
+		p.Parameters[k] = v
+	}
+	materials := append([]string{}, cfg.provenance.materials...)
+	if cfg.baseImage != "" {
+		materials = append(materials, cfg.baseImage)
+	}
+	for _, f := range materials {
+		if err = addFile(f, p.AddMaterial); err != nil {
+			return err
+		}
+	}
+	if err = addFile(cfg.output, p.AddSubject); err != nil {
+		return err
+	}
+	p.Finished = time.Now()
+	provenancePath := cfg.provenance.path
+	if provenancePath == "" {
+		provenancePath = cfg.output + provenanceSuffix
+	}
+	return writeFile(provenancePath, p.WriteInToto)
+}
+
+func addFile(filePath string, add func(name string, r io.Reader) error) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("cannot open file %s. Error: %w", filePath, err)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+	return add(filePath, f)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"konvoy-os-package-builder/bundle"
+)
+
+// quarantineSuffix is appended to the output tarball path to get the default quarantine directory.
+const quarantineSuffix = ".quarantine"
+
+// quarantinePackages takes the corrupt package files out of the loaded bundle and copies them to the quarantine
+// directory.
+func quarantinePackages(ctx context.Context, b *bundle.Bundle, dir string) error {
+	corrupt, err := bundle.QuarantineCorruptPackages(ctx, b)
+	if err != nil {
+		return err
+	}
+	if len(corrupt) == 0 {
+		return nil
+	}
+	if err = copyToQuarantine(corrupt, dir); err != nil {
+		return err
+	}
+	fmt.Printf("The following package files are corrupt. I copied them to %s and I'm going to download them "+
+		"again:\n%s\n\n", dir, printCorruptPackages(corrupt))
+	return nil
+}
+
+// verifyPackages verifies the fixed bundle before it's written. The corrupt package files are copied to
+// the quarantine directory.
+func verifyPackages(ctx context.Context, b *bundle.Bundle, dir string) error {
+	corrupt, err := bundle.VerifyPackages(ctx, b)
+	if err != nil {
+		return err
+	}
+	if len(corrupt) == 0 {
+		return nil
+	}
+	if err = copyToQuarantine(corrupt, dir); err != nil {
+		return err
+	}
+	return fmt.Errorf("the fixed bundle contains corrupt package files, I copied them to %s:\n%s", dir,
+		printCorruptPackages(corrupt))
+}
+
+func copyToQuarantine(corrupt []bundle.CorruptPackage, dir string) error {
+	for _, c := range corrupt {
+		if err := copyPackageFile(c.Package, path.Join(dir, c.Path)); err != nil {
+			return fmt.Errorf("cannot quarantine package file %s. Error: %w", c.Path, err)
+		}
+	}
+	return nil
+}
+
+func copyPackageFile(p *bundle.Package, filePath string) error {
+	from, err := p.Open()
+	if err != nil {
+		return err
+	}
+	//noinspection GoUnhandledErrorResult
+	defer from.Close()
+	if err = os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	to, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	//noinspection GoUnhandledErrorResult
+	defer to.Close()
+	_, err = io.Copy(to, from)
+	return err
+}
+
+func printCorruptPackages(corrupt []bundle.CorruptPackage) string {
+	lines := make([]string, len(corrupt))
+	for i, c := range corrupt {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"konvoy-os-package-builder/pkg/apt"
+)
+
+// Target is an OS release to build the bundle for.
+type Target struct {
+	// Name identifies the target and prefixes its output tarball, for example "ubuntu-18.04".
+	Name string `json:"name"`
+	// Release is the distro release, for example "bionic".
+	Release      string   `json:"release"`
+	Architecture string   `json:"architecture"`
+	Sources      []string `json:"sources"`
+	Keyrings     []string `json:"keyrings"`
+	// BaseImage is the dpkg status file or the package list of the target base image.
+	BaseImage string `json:"baseImage"`
+}
+
+// readTargets reads the targets file. The architecture is the default one of the targets without it.
+func readTargets(targetsPath, architecture string) ([]Target, error) {
+	data, err := os.ReadFile(targetsPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read targets file %s. Error: %w", targetsPath, err)
+	}
+	var targets []Target
+	if err = json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("cannot parse targets file %s. Error: %w", targetsPath, err)
+	}
+	names := make(map[string]bool)
+	for i, t := range targets {
+		if t.Name == "" {
+			return nil, fmt.Errorf("target #%d has no name", i+1)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("more than one target with name %s found", t.Name)
+		}
+		names[t.Name] = true
+		if len(t.Sources) == 0 {
+			return nil, fmt.Errorf("target %s has no package sources", t.Name)
+		}
+		if t.Architecture == "" {
+			targets[i].Architecture = architecture
+		}
+	}
+	return targets, nil
+}
+
+// buildTargets fixes the bundle against every target in an isolated APT root and writes one output tarball
+// per target. The output files of the configuration are prefixed with the target name. It prints the combined
+// summary and returns false if any target has failed. The targets left when the context is done are skipped.
+func buildTargets(ctx context.Context, source bundleSource, targets []Target, cfg buildConfig) bool {
+	ok := true
+	summary := make([]string, len(targets))
+	for i, t := range targets {
+		if err := ctx.Err(); err != nil {
+			ok = false
+			summary[i] = fmt.Sprintf("%s (%s): SKIPPED. Error: %v", t.Name, t.Release, err)
+			continue
+		}
+		targetOutput := targetPath(cfg.output, t)
+		fmt.Printf("Building the bundle for target %s (%s).\n\n", t.Name, t.Release)
+		res, err := fixBundle(ctx, source, buildConfig{
+			apt: apt.Config{Architecture: t.Architecture, Sources: t.Sources, Keyrings: t.Keyrings,
+				OperationTimeout: cfg.apt.OperationTimeout},
+			baseImage: t.BaseImage,
+			policy:    cfg.policy,
+			approver:  cfg.approver,
+			output:    targetOutput,
+			dot:       targetPath(cfg.dot, t),
+			mermaid:   targetPath(cfg.mermaid, t),
+			report:    targetPath(cfg.report, t),
+			spdx:      targetPath(cfg.spdx, t),
+			cyclonedx: targetPath(cfg.cyclonedx, t),
+			vulnerabilities: vulnerabilityConfig{
+				advisories:  cfg.vulnerabilities.advisories,
+				release:     t.Release,
+				preferFixed: cfg.vulnerabilities.preferFixed,
+			},
+			quarantine: targetPath(cfg.quarantine, t),
+			progress:   cfg.progress,
+			workDir:    targetPath(cfg.workDir, t),
+			provenance: provenanceConfig{
+				path:       targetPath(cfg.provenance.path, t),
+				parameters: targetParameters(cfg.provenance.parameters, t),
+				materials:  cfg.provenance.materials,
+			},
+		})
+		switch {
+		case err != nil:
+			ok = false
+			summary[i] = fmt.Sprintf("%s (%s): FAILED. Error: %v", t.Name, t.Release, err)
+		case len(res.Unresolved) > 0:
+			ok = false
+			summary[i] = fmt.Sprintf("%s (%s): %s, the following packages were not fixed: %s",
+				t.Name, t.Release, targetOutput, strings.Join(res.Unresolved, ", "))
+		default:
+			summary[i] = fmt.Sprintf("%s (%s): %s, all packages were fixed", t.Name, t.Release, targetOutput)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("Summary of the targets:\n%s\n", strings.Join(summary, "\n"))
+	return ok
+}
+
+// targetParameters adds the target name to the command line options of the run.
+func targetParameters(parameters map[string]string, t Target) map[string]string {
+	targetParameters := map[string]string{"target": t.Name}
+	for k, v := range parameters {
+		targetParameters[k] = v
+	}
+	return targetParameters
+}
+
+// targetPath prefixes the file name with the target name. Empty path stays empty.
+func targetPath(filePath string, t Target) string {
+	if filePath == "" {
+		return ""
+	}
+	return path.Join(path.Dir(filePath), t.Name+"_"+path.Base(filePath))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func Test_readTargets(t *testing.T) {
+	tests := []struct {
+		name         string
+		targets      string
+		architecture string
+		want         []Target
+		wantErr      bool
+	}{
+		{
+			name: "reads targets",
+			targets: `[
+				{"name": "ubuntu-18.04", "release": "bionic",
+					"sources": ["deb http://archive.ubuntu.com/ubuntu bionic main"]},
+				{"name": "ubuntu-20.04", "release": "focal", "architecture": "amd64",
+					"sources": ["deb http://archive.ubuntu.com/ubuntu focal main"], "baseImage": "focal.status"}
+			]`,
+			architecture: "arm64",
+			want: []Target{
+				{Name: "ubuntu-18.04", Release: "bionic", Architecture: "arm64",
+					Sources: []string{"deb http://archive.ubuntu.com/ubuntu bionic main"}},
+				{Name: "ubuntu-20.04", Release: "focal", Architecture: "amd64",
+					Sources: []string{"deb http://archive.ubuntu.com/ubuntu focal main"}, BaseImage: "focal.status"},
+			},
+		},
+		{
+			name:    "rejects target without name",
+			targets: `[{"sources": ["deb http://archive.ubuntu.com/ubuntu bionic main"]}]`,
+			wantErr: true,
+		},
+		{
+			name: "rejects duplicated names",
+			targets: `[
+				{"name": "ubuntu", "sources": ["deb http://archive.ubuntu.com/ubuntu bionic main"]},
+				{"name": "ubuntu", "sources": ["deb http://archive.ubuntu.com/ubuntu focal main"]}
+			]`,
+			wantErr: true,
+		},
+		{
+			name:    "rejects target without sources",
+			targets: `[{"name": "ubuntu-18.04"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "rejects invalid JSON",
+			targets: `{"name": "ubuntu-18.04"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetsPath := path.Join(t.TempDir(), "targets.json")
+			if err := os.WriteFile(targetsPath, []byte(tt.targets), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			got, err := readTargets(targetsPath, tt.architecture)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readTargets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_targetPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		want     string
+	}{
+		{
+			name:     "prefixes file name",
+			filePath: "konvoy_v1.8.3_amd64_debs.tar.gz",
+			want:     "ubuntu-18.04_konvoy_v1.8.3_amd64_debs.tar.gz",
+		},
+		{
+			name:     "keeps directory",
+			filePath: "/tmp/out/report.html",
+			want:     "/tmp/out/ubuntu-18.04_report.html",
+		},
+		{
+			name: "keeps empty path",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targetPath(tt.filePath, Target{Name: "ubuntu-18.04"}); got != tt.want {
+				t.Errorf("targetPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}